@@ -121,6 +121,7 @@ type build struct {
 }
 
 var ErrBuildDisappeared = errors.New("build-disappeared-from-db")
+var ErrBuildInputsImmutable = errors.New("build-inputs-immutable-once-completed")
 
 func (b *build) ID() int                      { return b.id }
 func (b *build) Name() string                 { return b.name }
@@ -726,6 +727,11 @@ func (b *build) SaveInput(input BuildInput) error {
 
 	defer Rollback(tx)
 
+	err = b.checkInputsMutable(tx)
+	if err != nil {
+		return err
+	}
+
 	row := pipelinesQuery.
 		Where(sq.Eq{"p.id": b.pipelineID}).
 		RunWith(tx).
@@ -767,6 +773,11 @@ func (b *build) UseInputs(inputs []BuildInput) error {
 
 	defer Rollback(tx)
 
+	err = b.checkInputsMutable(tx)
+	if err != nil {
+		return err
+	}
+
 	_, err = psql.Delete("build_inputs").
 		Where(sq.Eq{"build_id": b.id}).
 		RunWith(tx).
@@ -796,6 +807,30 @@ func (b *build) UseInputs(inputs []BuildInput) error {
 	return tx.Commit()
 }
 
+// checkInputsMutable returns ErrBuildInputsImmutable once the build has
+// completed, so that the inputs it ran with stay reproducible.
+func (b *build) checkInputsMutable(tx Tx) error {
+	var completed bool
+	err := psql.Select("completed").
+		From("builds").
+		Where(sq.Eq{"id": b.id}).
+		RunWith(tx).
+		QueryRow().
+		Scan(&completed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBuildDisappeared
+		}
+		return err
+	}
+
+	if completed {
+		return ErrBuildInputsImmutable
+	}
+
+	return nil
+}
+
 func (b *build) Resources() ([]BuildInput, []BuildOutput, error) {
 	inputs := []BuildInput{}
 	outputs := []BuildOutput{}
@@ -1086,6 +1086,39 @@ var _ = Describe("Build", func() {
 			Expect(actualBuildInput[0].VersionedResource).To(Equal(someVersionedResource))
 			Expect(actualBuildInput[1].VersionedResource).To(Equal(someWeirdResource))
 		})
+
+		Context("when the build has completed", func() {
+			BeforeEach(func() {
+				Expect(build.Finish(db.BuildStatusSucceeded)).To(Succeed())
+			})
+
+			It("does not change the inputs the build ran with", func() {
+				err := build.UseInputs([]db.BuildInput{
+					{
+						Name: "some-other-input",
+						VersionedResource: db.VersionedResource{
+							Resource: "some-other-resource",
+							Type:     "some-other-type",
+						},
+					},
+				})
+				Expect(err).To(Equal(db.ErrBuildInputsImmutable))
+
+				err = build.SaveInput(db.BuildInput{
+					Name: "some-other-input",
+					VersionedResource: db.VersionedResource{
+						Resource: "some-other-resource",
+						Type:     "some-other-type",
+					},
+				})
+				Expect(err).To(Equal(db.ErrBuildInputsImmutable))
+
+				actualBuildInput, err := build.GetVersionedResources()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(actualBuildInput)).To(Equal(1))
+				Expect(actualBuildInput[0].Resource).To(Equal("some-resource"))
+			})
+		})
 	})
 
 	Describe("FinishWithError", func() {
@@ -32,6 +32,8 @@
 // db/migration/migrations/1525442981_create_version_resources_check_order_index.up.sql
 // db/migration/migrations/1525724789_drop_reaper_addr_from_workers.down.sql
 // db/migration/migrations/1525724789_drop_reaper_addr_from_workers.up.sql
+// db/migration/migrations/1526986026_create_worker_request_rates.down.sql
+// db/migration/migrations/1526986026_create_worker_request_rates.up.sql
 // DO NOT EDIT!
 
 package migration
@@ -739,6 +741,46 @@ func _1525724789_drop_reaper_addr_from_workersUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1526986026_create_worker_request_ratesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x72\x75\xf7\xf4\xb3\xe6\xe2\x52\x50\x70\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\xcf\x2f\xca\x4e\x2d\x8a\x2f\x4a\x2d\x2c\x4d\x2d\x2e\x89\x2f\x4a\x2c\x49\x2d\xb6\xe6\xe2\x72\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x0c\x00\x36\x43\x17\x4f\x34\x00\x00\x00")
+
+func _1526986026_create_worker_request_ratesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1526986026_create_worker_request_ratesDownSql,
+		"1526986026_create_worker_request_rates.down.sql",
+	)
+}
+
+func _1526986026_create_worker_request_ratesDownSql() (*asset, error) {
+	bytes, err := _1526986026_create_worker_request_ratesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1526986026_create_worker_request_rates.down.sql", size: 52, mode: os.FileMode(420), modTime: time.Unix(1526986026, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __1526986026_create_worker_request_ratesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x54\x8e\xbd\x4e\xc4\x30\x10\x84\x7b\x3f\xc5\x94\x89\x74\x05\x7d\x2a\x9f\xb3\x87\x22\x1c\x07\xf9\x7c\xc5\x55\x51\x24\x56\x60\xa1\x38\x60\x2f\x0a\xe2\xe9\x11\xe4\xf8\xb9\x72\xb5\xdf\xcc\x7c\x7b\xba\xed\x5c\xa3\x14\x60\x3c\xe9\x40\x08\x7a\x6f\x09\xeb\x92\x9f\x39\x8f\x99\x5f\xdf\xb8\xc8\x98\x27\xe1\x82\x4a\x01\xf8\x79\xa5\x69\x66\x08\xbf\x0b\xdc\x10\xe0\x4e\xd6\xc2\xd3\x81\x3c\x39\x43\xc7\x0b\x54\x50\x7d\x61\x35\x06\x87\x96\x2c\x05\x82\xd1\x47\xa3\x5b\xda\x6d\x55\x31\x3d\x2c\xeb\x58\x64\xca\x02\x89\x33\x17\x99\xe6\x17\xac\x51\x9e\xbe\x4f\x7c\x2c\x89\x7f\xfb\xb7\xcc\x45\xa9\x20\x26\xe1\x47\xce\x7f\xf3\x2d\x1d\xf4\xc9\x06\xdc\x6c\xe0\xbd\xef\x7a\xed\xcf\xb8\xa3\x33\xaa\x7f\xd2\xbb\xab\xd9\x5a\x01\x75\xa3\x94\x19\xfa\xbe\x0b\x8d\xfa\x1c\x00\x83\xd5\x82\x02\x10\x01\x00\x00")
+
+func _1526986026_create_worker_request_ratesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1526986026_create_worker_request_ratesUpSql,
+		"1526986026_create_worker_request_rates.up.sql",
+	)
+}
+
+func _1526986026_create_worker_request_ratesUpSql() (*asset, error) {
+	bytes, err := _1526986026_create_worker_request_ratesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1526986026_create_worker_request_rates.up.sql", size: 272, mode: os.FileMode(420), modTime: time.Unix(1526986026, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -823,6 +865,8 @@ var _bindata = map[string]func() (*asset, error){
 	"1525442981_create_version_resources_check_order_index.up.sql":                             _1525442981_create_version_resources_check_order_indexUpSql,
 	"1525724789_drop_reaper_addr_from_workers.down.sql":                                        _1525724789_drop_reaper_addr_from_workersDownSql,
 	"1525724789_drop_reaper_addr_from_workers.up.sql":                                          _1525724789_drop_reaper_addr_from_workersUpSql,
+	"1526986026_create_worker_request_rates.down.sql":                                          _1526986026_create_worker_request_ratesDownSql,
+	"1526986026_create_worker_request_rates.up.sql":                                            _1526986026_create_worker_request_ratesUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -898,6 +942,8 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1525442981_create_version_resources_check_order_index.up.sql":                             &bintree{_1525442981_create_version_resources_check_order_indexUpSql, map[string]*bintree{}},
 	"1525724789_drop_reaper_addr_from_workers.down.sql":                                        &bintree{_1525724789_drop_reaper_addr_from_workersDownSql, map[string]*bintree{}},
 	"1525724789_drop_reaper_addr_from_workers.up.sql":                                          &bintree{_1525724789_drop_reaper_addr_from_workersUpSql, map[string]*bintree{}},
+	"1526986026_create_worker_request_rates.down.sql":                                          &bintree{_1526986026_create_worker_request_ratesDownSql, map[string]*bintree{}},
+	"1526986026_create_worker_request_rates.up.sql":                                            &bintree{_1526986026_create_worker_request_ratesUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,5 @@
+BEGIN;
+
+  DROP TABLE worker_request_rates;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+  CREATE TABLE worker_request_rates (
+    worker_name text NOT NULL REFERENCES workers (name) ON DELETE CASCADE,
+    window_start timestamp with time zone NOT NULL,
+    requests integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (worker_name, window_start)
+  );
+
+COMMIT;
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+//go:generate counterfeiter . WorkerRequestRates
+
+type WorkerRequestRates interface {
+	IncrementRequests(workerName string, windowStart time.Time) (int, error)
+}
+
+type workerRequestRates struct {
+	conn Conn
+}
+
+func NewWorkerRequestRates(conn Conn) WorkerRequestRates {
+	return &workerRequestRates{
+		conn: conn,
+	}
+}
+
+func (r *workerRequestRates) IncrementRequests(workerName string, windowStart time.Time) (int, error) {
+	var requests int
+
+	err := safeFindOrCreate(r.conn, func(tx Tx) error {
+		_, err := psql.Delete("worker_request_rates").
+			Where(sq.And{
+				sq.Eq{"worker_name": workerName},
+				sq.Lt{"window_start": windowStart},
+			}).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return err
+		}
+
+		err = psql.Update("worker_request_rates").
+			Set("requests", sq.Expr("requests + 1")).
+			Where(sq.Eq{
+				"worker_name":  workerName,
+				"window_start": windowStart,
+			}).
+			Suffix("RETURNING requests").
+			RunWith(tx).
+			QueryRow().
+			Scan(&requests)
+		if err == nil {
+			return nil
+		}
+
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		err = psql.Insert("worker_request_rates").
+			Columns("worker_name", "window_start", "requests").
+			Values(workerName, windowStart, 1).
+			Suffix("RETURNING requests").
+			RunWith(tx).
+			QueryRow().
+			Scan(&requests)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == pqUniqueViolationErrCode {
+				return ErrSafeRetryFindOrCreate
+			}
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,70 @@
+package db_test
+
+import (
+	"time"
+
+	"github.com/concourse/atc/db"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WorkerRequestRates", func() {
+	var (
+		replicaA db.WorkerRequestRates
+		replicaB db.WorkerRequestRates
+
+		replicaConn db.Conn
+		windowStart time.Time
+	)
+
+	BeforeEach(func() {
+		replicaA = db.NewWorkerRequestRates(dbConn)
+		replicaConn = postgresRunner.OpenConn()
+		replicaB = db.NewWorkerRequestRates(replicaConn)
+
+		windowStart = time.Now().Truncate(time.Minute)
+	})
+
+	AfterEach(func() {
+		Expect(replicaConn.Close()).To(Succeed())
+	})
+
+	Describe("IncrementRequests", func() {
+		It("counts the requests of every replica together", func() {
+			count, err := replicaA.IncrementRequests(defaultWorker.Name(), windowStart)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+
+			count, err = replicaB.IncrementRequests(defaultWorker.Name(), windowStart)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(2))
+
+			count, err = replicaA.IncrementRequests(defaultWorker.Name(), windowStart)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(3))
+		})
+
+		It("starts counting again in the next window", func() {
+			_, err := replicaA.IncrementRequests(defaultWorker.Name(), windowStart)
+			Expect(err).ToNot(HaveOccurred())
+
+			count, err := replicaB.IncrementRequests(defaultWorker.Name(), windowStart.Add(time.Minute))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("removes the worker's previous windows", func() {
+			_, err := replicaA.IncrementRequests(defaultWorker.Name(), windowStart)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = replicaA.IncrementRequests(defaultWorker.Name(), windowStart.Add(time.Minute))
+			Expect(err).ToNot(HaveOccurred())
+
+			var windows int
+			err = dbConn.QueryRow(`SELECT COUNT(*) FROM worker_request_rates`).Scan(&windows)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(windows).To(Equal(1))
+		})
+	})
+})
@@ -20,6 +20,9 @@ var FailedVolumes = Meter(0)
 var ContainersDeleted = Meter(0)
 var VolumesDeleted = Meter(0)
 
+var WorkerRequestRetriesExhausted = Meter(0)
+var WorkerSlowStreams = Meter(0)
+
 type SchedulingFullDuration struct {
 	PipelineName string
 	Duration     time.Duration
@@ -386,3 +389,22 @@ func (event HTTPResponseTime) Emit(logger lager.Logger) {
 		},
 	)
 }
+
+type WorkerDrainingConnections struct {
+	WorkerName  string
+	Connections int
+}
+
+func (event WorkerDrainingConnections) Emit(logger lager.Logger) {
+	emit(
+		logger.Session("worker-draining-connections"),
+		Event{
+			Name:  "worker draining connections",
+			Value: event.Connections,
+			State: EventStateOK,
+			Attributes: map[string]string{
+				"worker": event.WorkerName,
+			},
+		},
+	)
+}
@@ -93,6 +93,24 @@ func PeriodicallyEmit(logger lager.Logger, interval time.Duration) {
 			},
 		)
 
+		emit(
+			logger.Session("worker-request-retries-exhausted"),
+			Event{
+				Name:  "worker request retries exhausted",
+				Value: WorkerRequestRetriesExhausted.Delta(),
+				State: EventStateOK,
+			},
+		)
+
+		emit(
+			logger.Session("worker-slow-streams"),
+			Event{
+				Name:  "worker slow streams",
+				Value: WorkerSlowStreams.Delta(),
+				State: EventStateOK,
+			},
+		)
+
 		var memStats runtime.MemStats
 		runtime.ReadMemStats(&memStats)
 
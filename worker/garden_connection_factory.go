@@ -46,12 +46,12 @@ func (gcf *gardenConnectionFactory) BuildConnection() gconn.Connection {
 	}
 
 	httpClient := &http.Client{
-		Transport: &retryhttp.RetryRoundTripper{
+		Transport: transport.NewIdempotentRoundTripper(transport.NewRetryExhaustionRoundTripper(retryer, &retryhttp.RetryRoundTripper{
 			Logger:         gcf.logger.Session("retryable-http-client"),
 			BackOffFactory: gcf.retryBackOffFactory,
 			RoundTripper:   transport.NewGardenRoundTripper(gcf.workerName, gcf.workerHost, gcf.db, &http.Transport{DisableKeepAlives: true}),
 			Retryer:        retryer,
-		},
+		})),
 	}
 
 	hijackableClient := &retryhttp.RetryHijackableClient{
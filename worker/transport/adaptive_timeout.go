@@ -0,0 +1,172 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// adaptiveTimeoutSamples is the number of recent latencies kept per worker.
+const adaptiveTimeoutSamples = 100
+
+// AdaptiveTimeout derives a per-worker timeout from the 99th percentile of the
+// worker's recently observed latencies, multiplied by Multiplier and bounded
+// by Min and Max. A worker without observed latencies gets Max.
+type AdaptiveTimeout struct {
+	min        time.Duration
+	max        time.Duration
+	multiplier float64
+
+	latenciesL sync.Mutex
+	latencies  map[string][]time.Duration
+}
+
+func NewAdaptiveTimeout(min time.Duration, max time.Duration, multiplier float64) *AdaptiveTimeout {
+	return &AdaptiveTimeout{
+		min:        min,
+		max:        max,
+		multiplier: multiplier,
+		latencies:  map[string][]time.Duration{},
+	}
+}
+
+func (timeout *AdaptiveTimeout) Record(workerName string, latency time.Duration) {
+	timeout.latenciesL.Lock()
+	defer timeout.latenciesL.Unlock()
+
+	latencies := append(timeout.latencies[workerName], latency)
+	if len(latencies) > adaptiveTimeoutSamples {
+		latencies = latencies[len(latencies)-adaptiveTimeoutSamples:]
+	}
+
+	timeout.latencies[workerName] = latencies
+}
+
+func (timeout *AdaptiveTimeout) Timeout(workerName string) time.Duration {
+	timeout.latenciesL.Lock()
+	latencies := append([]time.Duration{}, timeout.latencies[workerName]...)
+	timeout.latenciesL.Unlock()
+
+	if len(latencies) == 0 {
+		return timeout.max
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	p99 := latencies[(len(latencies)*99-1)/100]
+
+	adapted := time.Duration(float64(p99) * timeout.multiplier)
+	if adapted < timeout.min {
+		return timeout.min
+	}
+
+	if adapted > timeout.max {
+		return timeout.max
+	}
+
+	return adapted
+}
+
+type adaptiveTimeoutRoundTripper struct {
+	workerName        string
+	clock             clock.Clock
+	timeout           *AdaptiveTimeout
+	innerRoundTripper http.RoundTripper
+}
+
+// NewAdaptiveTimeoutRoundTripper returns a http.RoundTripper which gives up on
+// a request to the named worker if its response headers have not arrived
+// within the worker's adaptive timeout. The latency of every response feeds
+// back into the timeout, as does the timeout itself for every request that
+// times out. Streaming the response body is not subject to the timeout.
+func NewAdaptiveTimeoutRoundTripper(workerName string, clock clock.Clock, timeout *AdaptiveTimeout, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &adaptiveTimeoutRoundTripper{
+		workerName:        workerName,
+		clock:             clock,
+		timeout:           timeout,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *adaptiveTimeoutRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(request.Context())
+
+	limit := c.timeout.Timeout(c.workerName)
+	timer := c.clock.NewTimer(limit)
+	headersReceived := make(chan struct{})
+
+	// whichever of the timer and the response comes first decides the outcome
+	var outcomeL sync.Mutex
+	var responded, timedOut bool
+
+	go func() {
+		select {
+		case <-timer.C():
+			outcomeL.Lock()
+			if !responded {
+				timedOut = true
+				cancel()
+			}
+			outcomeL.Unlock()
+		case <-headersReceived:
+		}
+	}()
+
+	started := c.clock.Now()
+
+	response, err := c.innerRoundTripper.RoundTrip(request.WithContext(ctx))
+
+	outcomeL.Lock()
+	responded = true
+	gaveUp := timedOut
+	outcomeL.Unlock()
+
+	timer.Stop()
+	close(headersReceived)
+
+	if gaveUp {
+		if err == nil {
+			response.Body.Close()
+		}
+
+		cancel()
+
+		// the actual latency is unknown but at least the timeout; recording
+		// that lets the timeout grow back when the worker gets slower
+		c.timeout.Record(c.workerName, limit)
+
+		return nil, ErrRequestTimedOut
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c.timeout.Record(c.workerName, c.clock.Since(started))
+
+	response.Body = &cancellingBody{
+		ReadCloser: response.Body,
+		cancel:     cancel,
+	}
+
+	return response, nil
+}
+
+type cancellingBody struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (body *cancellingBody) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
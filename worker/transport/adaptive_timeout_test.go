@@ -0,0 +1,138 @@
+package transport_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AdaptiveTimeout", func() {
+	var timeout *transport.AdaptiveTimeout
+
+	BeforeEach(func() {
+		timeout = transport.NewAdaptiveTimeout(100*time.Millisecond, 10*time.Second, 3)
+	})
+
+	It("uses the maximum for a worker without observed latencies", func() {
+		Expect(timeout.Timeout("some-worker")).To(Equal(10 * time.Second))
+	})
+
+	It("tracks the 99th percentile of observed latency", func() {
+		for i := 1; i <= 100; i++ {
+			timeout.Record("some-worker", time.Duration(i)*10*time.Millisecond)
+		}
+
+		Expect(timeout.Timeout("some-worker")).To(Equal(3 * 990 * time.Millisecond))
+		Expect(timeout.Timeout("some-other-worker")).To(Equal(10 * time.Second))
+	})
+
+	It("follows the latency as it changes", func() {
+		for i := 0; i < 100; i++ {
+			timeout.Record("some-worker", time.Second)
+		}
+
+		Expect(timeout.Timeout("some-worker")).To(Equal(3 * time.Second))
+
+		for i := 0; i < 100; i++ {
+			timeout.Record("some-worker", 500*time.Millisecond)
+		}
+
+		Expect(timeout.Timeout("some-worker")).To(Equal(1500 * time.Millisecond))
+	})
+
+	It("stays within the bounds", func() {
+		timeout.Record("fast-worker", time.Millisecond)
+		Expect(timeout.Timeout("fast-worker")).To(Equal(100 * time.Millisecond))
+
+		timeout.Record("slow-worker", time.Hour)
+		Expect(timeout.Timeout("slow-worker")).To(Equal(10 * time.Second))
+	})
+})
+
+var _ = Describe("AdaptiveTimeoutRoundTripper #RoundTrip", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		timeout          *transport.AdaptiveTimeout
+		roundTripper     http.RoundTripper
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		timeout = transport.NewAdaptiveTimeout(time.Second, time.Minute, 2)
+
+		roundTripper = transport.NewAdaptiveTimeoutRoundTripper("some-worker", fakeClock, timeout, fakeRoundTripper)
+	})
+
+	Context("when the response arrives in time", func() {
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+				fakeClock.Increment(5 * time.Second)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+				}, nil
+			}
+		})
+
+		It("returns it and adapts the timeout to its latency", func() {
+			response, err := roundTripper.RoundTrip(newBodyRequest(""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(response.Body.Close()).To(Succeed())
+
+			Expect(timeout.Timeout("some-worker")).To(Equal(10 * time.Second))
+		})
+	})
+
+	Context("when the response does not arrive within the timeout", func() {
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+				<-request.Context().Done()
+				return nil, request.Context().Err()
+			}
+		})
+
+		It("gives up on the request", func() {
+			errs := make(chan error)
+			go func() {
+				_, err := roundTripper.RoundTrip(newBodyRequest(""))
+				errs <- err
+			}()
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(errs).Should(Receive(Equal(transport.ErrRequestTimedOut)))
+		})
+
+		It("grows the timeout back when the worker has become slower", func() {
+			for i := 0; i < 100; i++ {
+				timeout.Record("some-worker", 500*time.Millisecond)
+			}
+
+			Expect(timeout.Timeout("some-worker")).To(Equal(time.Second))
+
+			for i := 0; i < 2; i++ {
+				errs := make(chan error)
+				go func() {
+					_, err := roundTripper.RoundTrip(newBodyRequest(""))
+					errs <- err
+				}()
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(errs).Should(Receive(Equal(transport.ErrRequestTimedOut)))
+			}
+
+			Expect(timeout.Timeout("some-worker")).To(Equal(2 * time.Second))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// BackpressureHeader is set by an overloaded worker on its responses to ask
+// for its requests to be spaced out, e.g. "500ms" for no more than two
+// requests a second.
+const BackpressureHeader = "X-Backpressure"
+
+type backpressureRoundTripper struct {
+	clock             clock.Clock
+	innerRoundTripper http.RoundTripper
+
+	spacingL sync.Mutex
+	spacing  time.Duration
+	next     time.Time
+}
+
+// NewBackpressureRoundTripper returns a http.RoundTripper which spaces out
+// requests to a worker as asked by the BackpressureHeader of its latest
+// response. A response without the header lifts the spacing again.
+func NewBackpressureRoundTripper(clock clock.Clock, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &backpressureRoundTripper{
+		clock:             clock,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *backpressureRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	wait := c.reserve()
+	if wait > 0 {
+		timer := c.clock.NewTimer(wait)
+
+		select {
+		case <-timer.C():
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		}
+	}
+
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	c.observe(response)
+
+	return response, nil
+}
+
+// reserve claims the next free slot for a request, returning how long to wait
+// for it.
+func (c *backpressureRoundTripper) reserve() time.Duration {
+	c.spacingL.Lock()
+	defer c.spacingL.Unlock()
+
+	now := c.clock.Now()
+	if c.spacing == 0 || !c.next.After(now) {
+		c.next = now.Add(c.spacing)
+		return 0
+	}
+
+	slot := c.next
+	c.next = slot.Add(c.spacing)
+
+	return slot.Sub(now)
+}
+
+func (c *backpressureRoundTripper) observe(response *http.Response) {
+	spacing, err := time.ParseDuration(response.Header.Get(BackpressureHeader))
+	if err != nil || spacing < 0 {
+		spacing = 0
+	}
+
+	c.spacingL.Lock()
+	defer c.spacingL.Unlock()
+
+	if spacing > c.spacing {
+		next := c.clock.Now().Add(spacing)
+		if next.After(c.next) {
+			c.next = next
+		}
+	}
+
+	c.spacing = spacing
+}
@@ -0,0 +1,127 @@
+package transport_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BackpressureRoundTripper #RoundTrip", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		backpressure chan string
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		signals := make(chan string, 10)
+		backpressure = signals
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			header := http.Header{}
+
+			select {
+			case spacing := <-signals:
+				header.Set(transport.BackpressureHeader, spacing)
+			default:
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+
+		roundTripper = transport.NewBackpressureRoundTripper(fakeClock, fakeRoundTripper)
+	})
+
+	sendInBackground := func(n int) {
+		for i := 0; i < n; i++ {
+			go func(roundTripper http.RoundTripper) {
+				defer GinkgoRecover()
+
+				_, err := roundTripper.RoundTrip(newBodyRequest(""))
+				Expect(err).NotTo(HaveOccurred())
+			}(roundTripper)
+		}
+	}
+
+	It("does not hold back requests until the worker signals backpressure", func() {
+		sendInBackground(3)
+
+		Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(3))
+		Expect(fakeClock.WatcherCount()).To(Equal(0))
+	})
+
+	Context("when the worker signals backpressure", func() {
+		BeforeEach(func() {
+			backpressure <- "1s"
+
+			_, err := roundTripper.RoundTrip(newBodyRequest(""))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("spaces out the following requests", func() {
+			backpressure <- "1s"
+			backpressure <- "1s"
+			backpressure <- "1s"
+
+			sendInBackground(3)
+
+			Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(1))
+
+			fakeClock.WaitForNWatchersAndIncrement(time.Second, 3)
+			Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(2))
+			Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(2))
+
+			fakeClock.Increment(time.Second)
+			Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(3))
+			Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(3))
+
+			fakeClock.Increment(time.Second)
+			Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(4))
+		})
+
+		It("restores the full rate once the worker stops signalling", func() {
+			fakeClock.Increment(time.Second)
+
+			_, err := roundTripper.RoundTrip(newBodyRequest(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			sendInBackground(3)
+
+			Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(5))
+		})
+
+		It("gives up waiting when the request is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			errs := make(chan error, 1)
+			go func() {
+				_, err := roundTripper.RoundTrip(newBodyRequest("").WithContext(ctx))
+				errs <- err
+			}()
+
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+			cancel()
+
+			Eventually(errs).Should(Receive(Equal(context.Canceled)))
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+type compressingRoundTripper struct {
+	threshold         int64
+	innerRoundTripper http.RoundTripper
+}
+
+// NewCompressingRoundTripper returns a http.RoundTripper which gzips request
+// bodies larger than threshold bytes. Smaller bodies, and bodies of unknown
+// length, are sent as-is.
+func NewCompressingRoundTripper(threshold int64, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &compressingRoundTripper{
+		threshold:         threshold,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *compressingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil || request.ContentLength <= c.threshold {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	return c.innerRoundTripper.RoundTrip(compressRequest(request, "gzip", func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	}))
+}
+
+// compressRequest returns a copy of request whose body is streamed through
+// the compressor returned by newWriter and labelled with the given encoding.
+func compressRequest(request *http.Request, encoding string, newWriter func(io.Writer) (io.WriteCloser, error)) *http.Request {
+	body, writer := io.Pipe()
+
+	go func() {
+		defer request.Body.Close()
+
+		compressor, err := newWriter(writer)
+		if err == nil {
+			_, err = io.Copy(compressor, request.Body)
+			if err == nil {
+				err = compressor.Close()
+			}
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set("Content-Encoding", encoding)
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+	updatedRequest.Body = body
+	updatedRequest.ContentLength = -1
+
+	// the compressed stream can't be rewound, and replaying the caller's raw
+	// body under Content-Encoding would corrupt it
+	updatedRequest.GetBody = nil
+
+	return &updatedRequest
+}
@@ -0,0 +1,104 @@
+package transport_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompressingRoundTripper #RoundTrip", func() {
+	var (
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+		request          *http.Request
+
+		sentRequest *http.Request
+		sentHeader  http.Header
+		sentBody    []byte
+	)
+
+	BeforeEach(func() {
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			sentRequest = request
+			sentHeader = request.Header
+
+			var err error
+			sentBody, err = ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			return &http.Response{StatusCode: http.StatusTeapot}, nil
+		}
+
+		roundTripper = transport.NewCompressingRoundTripper(10, fakeRoundTripper)
+	})
+
+	JustBeforeEach(func() {
+		response, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(Equal(&http.Response{StatusCode: http.StatusTeapot}))
+	})
+
+	Context("when the body is above the threshold", func() {
+		BeforeEach(func() {
+			request = newBodyRequest("some-large-body")
+			request.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader("some-large-body")), nil
+			}
+		})
+
+		It("sends the body gzip-compressed", func() {
+			Expect(sentHeader.Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(sentHeader.Get("Content-Type")).To(Equal("application/json"))
+
+			gzipReader, err := gzip.NewReader(bytes.NewReader(sentBody))
+			Expect(err).NotTo(HaveOccurred())
+
+			decompressed, err := ioutil.ReadAll(gzipReader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(decompressed)).To(Equal("some-large-body"))
+		})
+
+		It("does not let the compressed request be replayed with the raw body", func() {
+			Expect(sentRequest.GetBody).To(BeNil())
+		})
+
+		It("does not modify the original request", func() {
+			Expect(request.Header.Get("Content-Encoding")).To(BeEmpty())
+		})
+	})
+
+	Context("when the body is below the threshold", func() {
+		BeforeEach(func() {
+			request = newBodyRequest("small")
+		})
+
+		It("sends the body raw", func() {
+			Expect(sentHeader.Get("Content-Encoding")).To(BeEmpty())
+			Expect(string(sentBody)).To(Equal("small"))
+		})
+	})
+})
+
+func newBodyRequest(body string) *http.Request {
+	requestURL, err := url.Parse("http://1.2.3.4/something")
+	Expect(err).NotTo(HaveOccurred())
+
+	return &http.Request{
+		Method:        "PUT",
+		URL:           requestURL,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CorrelationIDHeader links a worker request to the build and step it was
+// made for, so that the worker's logs can be matched up with the build's.
+const CorrelationIDHeader = "X-Correlation-Id"
+
+type correlationIDKey struct{}
+
+// WithBuildStep returns a copy of ctx which makes requests sent with it carry
+// a correlation id for the given build and step.
+func WithBuildStep(ctx context.Context, buildID int, stepName string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, fmt.Sprintf("build-%d/%s", buildID, stepName))
+}
+
+// CorrelationIDFromContext returns the correlation id carried by ctx, if any.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	correlationID, found := ctx.Value(correlationIDKey{}).(string)
+	return correlationID, found
+}
+
+type correlatingRoundTripper struct {
+	innerRoundTripper http.RoundTripper
+}
+
+// NewCorrelatingRoundTripper returns a http.RoundTripper which attaches the
+// correlation id carried by a request's context to the request. Requests
+// which already carry a correlation id are passed through unchanged.
+func NewCorrelatingRoundTripper(innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &correlatingRoundTripper{
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *correlatingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get(CorrelationIDHeader) != "" {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	correlationID, found := CorrelationIDFromContext(request.Context())
+	if !found {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set(CorrelationIDHeader, correlationID)
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+
+	return c.innerRoundTripper.RoundTrip(&updatedRequest)
+}
@@ -0,0 +1,81 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CorrelatingRoundTripper #RoundTrip", func() {
+	var (
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+		request          *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripReturns(&http.Response{StatusCode: http.StatusTeapot}, nil)
+
+		roundTripper = transport.NewCorrelatingRoundTripper(fakeRoundTripper)
+
+		requestURL, err := url.Parse("http://1.2.3.4/something")
+		Expect(err).NotTo(HaveOccurred())
+
+		request = &http.Request{
+			URL: requestURL,
+		}
+	})
+
+	Context("when the request is made for a build step", func() {
+		BeforeEach(func() {
+			request = request.WithContext(transport.WithBuildStep(context.Background(), 42, "some-step"))
+		})
+
+		It("attaches a correlation id for the build and step", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get(transport.CorrelationIDHeader)).To(Equal("build-42/some-step"))
+		})
+
+		It("does not modify the original request", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(request.Header.Get(transport.CorrelationIDHeader)).To(BeEmpty())
+		})
+
+		Context("when the request already carries a correlation id", func() {
+			BeforeEach(func() {
+				request.Header = http.Header{}
+				request.Header.Set(transport.CorrelationIDHeader, "some-correlation-id")
+			})
+
+			It("leaves it alone", func() {
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+				Expect(actualRequest.Header.Get(transport.CorrelationIDHeader)).To(Equal("some-correlation-id"))
+			})
+		})
+	})
+
+	Context("when the request is not made for a build step", func() {
+		It("sends it without a correlation id", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get(transport.CorrelationIDHeader)).To(BeEmpty())
+		})
+	})
+})
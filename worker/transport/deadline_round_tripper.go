@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// RequestTimeoutHeader tells the worker how many milliseconds are left before
+// the caller gives up on the request, so that it can abort work nobody will
+// wait for.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+type deadlineRoundTripper struct {
+	clock             clock.Clock
+	innerRoundTripper http.RoundTripper
+}
+
+// NewDeadlineRoundTripper returns a http.RoundTripper which propagates the
+// deadline of a request's context to the worker. Requests whose deadline has
+// already passed are not sent at all.
+func NewDeadlineRoundTripper(clock clock.Clock, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &deadlineRoundTripper{
+		clock:             clock,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *deadlineRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	deadline, hasDeadline := request.Context().Deadline()
+	if !hasDeadline {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	remaining := deadline.Sub(c.clock.Now())
+	if remaining <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set(RequestTimeoutHeader, strconv.FormatInt(int64(remaining/time.Millisecond), 10))
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+
+	return c.innerRoundTripper.RoundTrip(&updatedRequest)
+}
@@ -0,0 +1,90 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeadlineRoundTripper #RoundTrip", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+		request          *http.Request
+		response         *http.Response
+		err              error
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripReturns(&http.Response{StatusCode: http.StatusTeapot}, nil)
+
+		roundTripper = transport.NewDeadlineRoundTripper(fakeClock, fakeRoundTripper)
+
+		requestURL, err := url.Parse("http://1.2.3.4/something")
+		Expect(err).NotTo(HaveOccurred())
+
+		request = &http.Request{
+			URL: requestURL,
+		}
+	})
+
+	JustBeforeEach(func() {
+		response, err = roundTripper.RoundTrip(request)
+	})
+
+	Context("when the request's context has a deadline", func() {
+		var cancel context.CancelFunc
+
+		BeforeEach(func() {
+			var ctx context.Context
+			ctx, cancel = context.WithDeadline(context.Background(), fakeClock.Now().Add(90*time.Second))
+
+			fakeClock.Increment(30 * time.Second)
+
+			request = request.WithContext(ctx)
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("sends the remaining budget in the timeout header", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response).To(Equal(&http.Response{StatusCode: http.StatusTeapot}))
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get(transport.RequestTimeoutHeader)).To(Equal("60000"))
+		})
+
+		Context("when the deadline has already passed", func() {
+			BeforeEach(func() {
+				fakeClock.Increment(time.Minute)
+			})
+
+			It("fails without sending the request", func() {
+				Expect(err).To(Equal(context.DeadlineExceeded))
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Context("when the request's context has no deadline", func() {
+		It("sends the request without a timeout header", func() {
+			Expect(err).NotTo(HaveOccurred())
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get(transport.RequestTimeoutHeader)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// CompressionDictionaryHeader identifies the dictionary a request body was
+// compressed with, as returned by DictionaryID. A worker that does not have
+// the dictionary responds with 415 Unsupported Media Type.
+const CompressionDictionaryHeader = "X-Compression-Dictionary"
+
+// DictionaryID returns the identifier of a compression dictionary.
+func DictionaryID(dictionary []byte) string {
+	sum := sha256.Sum256(dictionary)
+	return hex.EncodeToString(sum[:])
+}
+
+type dictionaryCompressingRoundTripper struct {
+	dictionary        []byte
+	dictionaryID      string
+	threshold         int64
+	innerRoundTripper http.RoundTripper
+
+	unsupportedL sync.Mutex
+	unsupported  bool
+}
+
+// NewDictionaryCompressingRoundTripper returns a http.RoundTripper which
+// deflates request bodies larger than threshold bytes using a dictionary
+// shared with the worker, e.g. one built from typical container specs, so
+// that repeated similar payloads compress better than on their own.
+//
+// Bodies are buffered so that a request the worker rejects for lack of the
+// dictionary can be resent as-is; bodies too large to buffer are always sent
+// as-is. Once the worker has rejected the dictionary, all further bodies are
+// sent as-is.
+func NewDictionaryCompressingRoundTripper(dictionary []byte, threshold int64, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &dictionaryCompressingRoundTripper{
+		dictionary:        dictionary,
+		dictionaryID:      DictionaryID(dictionary),
+		threshold:         threshold,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *dictionaryCompressingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil || request.ContentLength <= c.threshold || request.ContentLength > maxBufferedBodySize || c.isUnsupported() {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	compressedRequest := compressRequest(copyRequest(request, body), "deflate", func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriterDict(w, flate.DefaultCompression, c.dictionary)
+	})
+
+	compressedRequest.Header.Set(CompressionDictionaryHeader, c.dictionaryID)
+
+	response, err := c.innerRoundTripper.RoundTrip(compressedRequest)
+	if err != nil || response.StatusCode != http.StatusUnsupportedMediaType {
+		return response, err
+	}
+
+	response.Body.Close()
+
+	c.unsupportedL.Lock()
+	c.unsupported = true
+	c.unsupportedL.Unlock()
+
+	return c.innerRoundTripper.RoundTrip(copyRequest(request, body))
+}
+
+func (c *dictionaryCompressingRoundTripper) isUnsupported() bool {
+	c.unsupportedL.Lock()
+	defer c.unsupportedL.Unlock()
+
+	return c.unsupported
+}
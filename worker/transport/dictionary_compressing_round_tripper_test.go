@@ -0,0 +1,147 @@
+package transport_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DictionaryCompressingRoundTripper #RoundTrip", func() {
+	var (
+		dictionary       []byte
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		knownDictionaries map[string][]byte
+		received          []string
+	)
+
+	BeforeEach(func() {
+		dictionary = []byte(`{"handle":"","platform":"linux","image":{"url":"docker:///concourse/buildroot"},"privileged":false,"bind_mounts":[{"src_path":"/var/lib/concourse/certs","dst_path":"/etc/ssl/certs","mode":0}],"env":["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"]}`)
+
+		knownDictionaries = map[string][]byte{
+			transport.DictionaryID(dictionary): dictionary,
+		}
+
+		received = nil
+
+		// a fake worker which decompresses bodies with the dictionaries it has
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			body := request.Body
+
+			if request.Header.Get("Content-Encoding") == "deflate" {
+				workerDictionary, found := knownDictionaries[request.Header.Get(transport.CompressionDictionaryHeader)]
+				if !found {
+					return &http.Response{
+						StatusCode: http.StatusUnsupportedMediaType,
+						Body:       ioutil.NopCloser(strings.NewReader("")),
+					}, nil
+				}
+
+				body = flate.NewReaderDict(body, workerDictionary)
+			}
+
+			decompressed, err := ioutil.ReadAll(body)
+			Expect(err).NotTo(HaveOccurred())
+
+			received = append(received, string(decompressed))
+
+			return &http.Response{StatusCode: http.StatusTeapot}, nil
+		}
+
+		roundTripper = transport.NewDictionaryCompressingRoundTripper(dictionary, 10, fakeRoundTripper)
+	})
+
+	Context("when the body is above the threshold", func() {
+		var payload string
+
+		BeforeEach(func() {
+			payload = `{"handle":"some-handle","platform":"linux","image":{"url":"docker:///concourse/buildroot"},"privileged":false,"bind_mounts":[{"src_path":"/var/lib/concourse/certs","dst_path":"/etc/ssl/certs","mode":0}],"env":["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin","FOO=bar"]}`
+		})
+
+		It("sends it compressed with the shared dictionary", func() {
+			response, err := roundTripper.RoundTrip(newBodyRequest(payload))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get("Content-Encoding")).To(Equal("deflate"))
+			Expect(actualRequest.Header.Get(transport.CompressionDictionaryHeader)).To(Equal(transport.DictionaryID(dictionary)))
+
+			Expect(received).To(Equal([]string{payload}))
+		})
+
+		It("compresses better than without the dictionary", func() {
+			withoutDictionary := new(bytes.Buffer)
+			writer, err := flate.NewWriter(withoutDictionary, flate.DefaultCompression)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = writer.Write([]byte(payload))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.Close()).To(Succeed())
+
+			var sentBody []byte
+			fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+				sentBody, err = ioutil.ReadAll(request.Body)
+				Expect(err).NotTo(HaveOccurred())
+				return &http.Response{StatusCode: http.StatusTeapot}, nil
+			}
+
+			_, err = roundTripper.RoundTrip(newBodyRequest(payload))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(len(sentBody)).To(BeNumerically("<", withoutDictionary.Len()))
+		})
+
+		Context("when the worker does not have the dictionary", func() {
+			BeforeEach(func() {
+				knownDictionaries = map[string][]byte{}
+			})
+
+			It("resends the body as-is", func() {
+				response, err := roundTripper.RoundTrip(newBodyRequest(payload))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(2))
+				actualRequest := fakeRoundTripper.RoundTripArgsForCall(1)
+				Expect(actualRequest.Header.Get("Content-Encoding")).To(BeEmpty())
+				Expect(actualRequest.Header.Get(transport.CompressionDictionaryHeader)).To(BeEmpty())
+				Expect(received).To(Equal([]string{payload}))
+			})
+
+			It("sends later bodies as-is straight away", func() {
+				_, err := roundTripper.RoundTrip(newBodyRequest(payload))
+				Expect(err).NotTo(HaveOccurred())
+
+				response, err := roundTripper.RoundTrip(newBodyRequest(payload))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(3))
+				actualRequest := fakeRoundTripper.RoundTripArgsForCall(2)
+				Expect(actualRequest.Header.Get("Content-Encoding")).To(BeEmpty())
+				Expect(received).To(Equal([]string{payload, payload}))
+			})
+		})
+	})
+
+	Context("when the body is below the threshold", func() {
+		It("sends it as-is", func() {
+			_, err := roundTripper.RoundTrip(newBodyRequest("small"))
+			Expect(err).NotTo(HaveOccurred())
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get("Content-Encoding")).To(BeEmpty())
+			Expect(received).To(Equal([]string{"small"}))
+		})
+	})
+})
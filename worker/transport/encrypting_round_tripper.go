@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/concourse/atc/db/encryption"
+)
+
+// EncryptionNonceHeader carries the nonce a payload was encrypted with. Its
+// presence marks the body as encrypted, in either direction.
+const EncryptionNonceHeader = "X-Encryption-Nonce"
+
+type encryptingRoundTripper struct {
+	key               encryption.Strategy
+	innerRoundTripper http.RoundTripper
+}
+
+// NewEncryptingRoundTripper returns a http.RoundTripper which encrypts
+// request bodies with the key shared with the worker, independent of any TLS
+// in between, and decrypts response bodies the worker encrypted in turn.
+//
+// Each body is buffered in order to encrypt it, so it is meant for small
+// sensitive payloads such as container specs, not for streams.
+func NewEncryptingRoundTripper(key encryption.Strategy, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &encryptingRoundTripper{
+		key:               key,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *encryptingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		var err error
+		request, err = c.encryptRequest(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := response.Header.Get(EncryptionNonceHeader)
+	if nonce == "" {
+		return response, nil
+	}
+
+	ciphertext, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := c.key.Decrypt(string(ciphertext), &nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(plaintext))
+	response.ContentLength = int64(len(plaintext))
+
+	return response, nil
+}
+
+func (c *encryptingRoundTripper) encryptRequest(request *http.Request) (*http.Request, error) {
+	plaintext, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, nonce, err := c.key.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	if nonce != nil {
+		updatedHeader.Set(EncryptionNonceHeader, *nonce)
+	}
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+	updatedRequest.Body = ioutil.NopCloser(strings.NewReader(ciphertext))
+	updatedRequest.ContentLength = int64(len(ciphertext))
+
+	// a replayed request must send the ciphertext, not the caller's plaintext
+	updatedRequest.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(ciphertext)), nil
+	}
+
+	return &updatedRequest, nil
+}
@@ -0,0 +1,138 @@
+package transport_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/concourse/atc/db/encryption"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EncryptingRoundTripper #RoundTrip", func() {
+	var (
+		key              *encryption.Key
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		sentRequest *http.Request
+		sentHeader  http.Header
+		sentBody    []byte
+	)
+
+	newKey := func(secret string) *encryption.Key {
+		block, err := aes.NewCipher([]byte(secret))
+		Expect(err).NotTo(HaveOccurred())
+
+		aesgcm, err := cipher.NewGCM(block)
+		Expect(err).NotTo(HaveOccurred())
+
+		return encryption.NewKey(aesgcm)
+	}
+
+	BeforeEach(func() {
+		key = newKey("AES256Key-32Characters1234567890")
+
+		// a fake worker holding the key, which echoes the payload back encrypted
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			sentRequest = request
+			sentHeader = request.Header
+
+			var err error
+			sentBody, err = ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			nonce := request.Header.Get(transport.EncryptionNonceHeader)
+			payload, err := key.Decrypt(string(sentBody), &nonce)
+			Expect(err).NotTo(HaveOccurred())
+
+			ciphertext, responseNonce, err := key.Encrypt([]byte("echo: " + string(payload)))
+			Expect(err).NotTo(HaveOccurred())
+
+			header := http.Header{}
+			header.Set(transport.EncryptionNonceHeader, *responseNonce)
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       ioutil.NopCloser(strings.NewReader(ciphertext)),
+			}, nil
+		}
+
+		roundTripper = transport.NewEncryptingRoundTripper(key, fakeRoundTripper)
+	})
+
+	It("round-trips the payload encrypted", func() {
+		response, err := roundTripper.RoundTrip(newBodyRequest(`{"env":["SECRET=sauce"]}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sentHeader.Get(transport.EncryptionNonceHeader)).NotTo(BeEmpty())
+		Expect(sentHeader.Get("Content-Type")).To(Equal("application/json"))
+		Expect(string(sentBody)).NotTo(ContainSubstring("sauce"))
+
+		Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte(`echo: {"env":["SECRET=sauce"]}`)))
+	})
+
+	It("replays the encrypted payload rather than the caller's", func() {
+		request := newBodyRequest("some-secret-body")
+		request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("some-secret-body")), nil
+		}
+
+		_, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		replayedBody, err := sentRequest.GetBody()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.ReadAll(replayedBody)).To(Equal(sentBody))
+	})
+
+	Context("when the worker's response is not encrypted", func() {
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripStub = nil
+			fakeRoundTripper.RoundTripReturns(&http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil)
+		})
+
+		It("returns it as-is", func() {
+			response, err := roundTripper.RoundTrip(newBodyRequest("some-body"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+		})
+	})
+
+	Context("when the response was encrypted with another key", func() {
+		BeforeEach(func() {
+			roundTripper = transport.NewEncryptingRoundTripper(newKey("someOtherKey-32Characters1234567"), fakeRoundTripper)
+
+			fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+				ciphertext, nonce, err := key.Encrypt([]byte("some-payload"))
+				Expect(err).NotTo(HaveOccurred())
+
+				header := http.Header{}
+				header.Set(transport.EncryptionNonceHeader, *nonce)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       ioutil.NopCloser(strings.NewReader(ciphertext)),
+				}, nil
+			}
+		})
+
+		It("returns an error", func() {
+			_, err := roundTripper.RoundTrip(newBodyRequest("some-body"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// ErrorRateTracker keeps the outcome of each worker's requests over a sliding
+// window so that error-prone workers can be avoided.
+type ErrorRateTracker struct {
+	clock  clock.Clock
+	window time.Duration
+
+	resultsL sync.Mutex
+	results  map[string][]requestResult
+}
+
+type requestResult struct {
+	at     time.Time
+	failed bool
+}
+
+func NewErrorRateTracker(clock clock.Clock, window time.Duration) *ErrorRateTracker {
+	return &ErrorRateTracker{
+		clock:   clock,
+		window:  window,
+		results: map[string][]requestResult{},
+	}
+}
+
+func (tracker *ErrorRateTracker) Record(workerName string, failed bool) {
+	tracker.resultsL.Lock()
+	defer tracker.resultsL.Unlock()
+
+	results := tracker.recentResults(workerName)
+
+	tracker.results[workerName] = append(results, requestResult{
+		at:     tracker.clock.Now(),
+		failed: failed,
+	})
+}
+
+// ErrorRate returns the fraction of the worker's requests within the window
+// that failed. A worker without any recent requests has an error rate of 0.
+func (tracker *ErrorRateTracker) ErrorRate(workerName string) float64 {
+	tracker.resultsL.Lock()
+	defer tracker.resultsL.Unlock()
+
+	results := tracker.recentResults(workerName)
+	tracker.results[workerName] = results
+
+	if len(results) == 0 {
+		return 0
+	}
+
+	failures := 0
+	for _, result := range results {
+		if result.failed {
+			failures++
+		}
+	}
+
+	return float64(failures) / float64(len(results))
+}
+
+// recentResults must be called with resultsL held.
+func (tracker *ErrorRateTracker) recentResults(workerName string) []requestResult {
+	results := tracker.results[workerName]
+
+	cutoff := tracker.clock.Now().Add(-tracker.window)
+	for len(results) > 0 && !results[0].at.After(cutoff) {
+		results = results[1:]
+	}
+
+	return results
+}
@@ -0,0 +1,48 @@
+package transport_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ErrorRateTracker", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		tracker   *transport.ErrorRateTracker
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		tracker = transport.NewErrorRateTracker(fakeClock, time.Minute)
+	})
+
+	It("returns 0 for a worker without requests", func() {
+		Expect(tracker.ErrorRate("some-worker")).To(Equal(0.0))
+	})
+
+	It("returns the fraction of failed requests", func() {
+		tracker.Record("some-worker", true)
+		tracker.Record("some-worker", false)
+		tracker.Record("some-worker", false)
+		tracker.Record("some-worker", true)
+
+		Expect(tracker.ErrorRate("some-worker")).To(Equal(0.5))
+		Expect(tracker.ErrorRate("some-other-worker")).To(Equal(0.0))
+	})
+
+	It("forgets requests older than the window", func() {
+		tracker.Record("some-worker", true)
+
+		fakeClock.Increment(30 * time.Second)
+		tracker.Record("some-worker", false)
+		Expect(tracker.ErrorRate("some-worker")).To(Equal(0.5))
+
+		fakeClock.Increment(30 * time.Second)
+		Expect(tracker.ErrorRate("some-worker")).To(Equal(0.0))
+	})
+})
@@ -1,6 +1,20 @@
 package transport
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoWorkersAvailable  = errors.New("no workers available")
+	ErrWorkerInMaintenance = errors.New("worker is in its maintenance window")
+	ErrLoadShed            = errors.New("request rejected to shed load")
+	ErrWorkerEjected       = errors.New("worker is ejected as a latency outlier")
+	ErrRequestTimedOut     = errors.New("timed out waiting for worker response")
+	ErrLatencyBudgetSpent  = errors.New("latency budget for worker requests is spent")
+	ErrRequestReplayed     = errors.New("request nonce is reused or expired")
+	ErrStreamTooSlow       = errors.New("worker stream fell below its throughput floor")
+)
 
 type WorkerMissingError struct {
 	WorkerName string
@@ -18,3 +32,11 @@ type WorkerUnreachableError struct {
 func (e WorkerUnreachableError) Error() string {
 	return fmt.Sprintf("worker '%s' is unreachable (state is '%s')", e.WorkerName, e.WorkerState)
 }
+
+type WorkerDrainingError struct {
+	WorkerName string
+}
+
+func (e WorkerDrainingError) Error() string {
+	return fmt.Sprintf("worker '%s' is being drained", e.WorkerName)
+}
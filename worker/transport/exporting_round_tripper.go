@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+//go:generate counterfeiter . RequestExporter
+
+// RequestExporter sends a record of every completed worker request to an
+// external sink.
+type RequestExporter interface {
+	Export(RequestRecord)
+}
+
+type RequestRecord struct {
+	WorkerName string
+	Method     string
+	Path       string
+
+	// StatusCode is 0 if the request failed without a response.
+	StatusCode int
+	Err        error
+
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+}
+
+type exportingRoundTripper struct {
+	workerName        string
+	clock             clock.Clock
+	exporter          RequestExporter
+	innerRoundTripper http.RoundTripper
+}
+
+// NewExportingRoundTripper returns a http.RoundTripper which exports a record
+// of each request to the named worker once it has completed, i.e. once its
+// response body has been closed.
+func NewExportingRoundTripper(workerName string, clock clock.Clock, exporter RequestExporter, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &exportingRoundTripper{
+		workerName:        workerName,
+		clock:             clock,
+		exporter:          exporter,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *exportingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	record := &exportedRequest{
+		exporter: c.exporter,
+		clock:    c.clock,
+		started:  c.clock.Now(),
+		record: RequestRecord{
+			WorkerName: c.workerName,
+			Method:     request.Method,
+			Path:       request.URL.Path,
+		},
+	}
+
+	if request.Body != nil {
+		updatedRequest := *request
+		updatedRequest.Body = &countingReadCloser{
+			ReadCloser: request.Body,
+			count:      record.addSent,
+		}
+
+		request = &updatedRequest
+	}
+
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil {
+		record.finish(0, err)
+		return nil, err
+	}
+
+	response.Body = &countingReadCloser{
+		ReadCloser: response.Body,
+		count:      record.addReceived,
+		closed: func() {
+			record.finish(response.StatusCode, nil)
+		},
+	}
+
+	return response, nil
+}
+
+type exportedRequest struct {
+	exporter RequestExporter
+	clock    clock.Clock
+	started  time.Time
+
+	recordL sync.Mutex
+	record  RequestRecord
+
+	finishOnce sync.Once
+}
+
+func (request *exportedRequest) addSent(n int) {
+	request.recordL.Lock()
+	request.record.BytesSent += int64(n)
+	request.recordL.Unlock()
+}
+
+func (request *exportedRequest) addReceived(n int) {
+	request.recordL.Lock()
+	request.record.BytesReceived += int64(n)
+	request.recordL.Unlock()
+}
+
+func (request *exportedRequest) finish(statusCode int, err error) {
+	request.finishOnce.Do(func() {
+		request.recordL.Lock()
+		record := request.record
+		request.recordL.Unlock()
+
+		record.StatusCode = statusCode
+		record.Err = err
+		record.Duration = request.clock.Since(request.started)
+
+		request.exporter.Export(record)
+	})
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+
+	count  func(int)
+	closed func()
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count(n)
+	return n, err
+}
+
+func (r *countingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+
+	if r.closed != nil {
+		r.closed()
+	}
+
+	return err
+}
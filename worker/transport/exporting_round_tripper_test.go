@@ -0,0 +1,92 @@
+package transport_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExportingRoundTripper #RoundTrip", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeExporter     *transportfakes.FakeRequestExporter
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		fakeExporter = new(transportfakes.FakeRequestExporter)
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			_, err := ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClock.Increment(time.Second)
+
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       ioutil.NopCloser(strings.NewReader("some-response")),
+			}, nil
+		}
+
+		roundTripper = transport.NewExportingRoundTripper("some-worker", fakeClock, fakeExporter, fakeRoundTripper)
+	})
+
+	It("exports a record once the request has completed", func() {
+		response, err := roundTripper.RoundTrip(newBodyRequest("some-request"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeClock.Increment(time.Second)
+		Expect(fakeExporter.ExportCallCount()).To(BeZero())
+
+		Expect(response.Body.Close()).To(Succeed())
+		Expect(response.Body.Close()).To(Succeed())
+
+		Expect(fakeExporter.ExportCallCount()).To(Equal(1))
+		Expect(fakeExporter.ExportArgsForCall(0)).To(Equal(transport.RequestRecord{
+			WorkerName:    "some-worker",
+			Method:        "PUT",
+			Path:          "/something",
+			StatusCode:    http.StatusCreated,
+			Duration:      2 * time.Second,
+			BytesSent:     int64(len("some-request")),
+			BytesReceived: int64(len("some-response")),
+		}))
+	})
+
+	Context("when the request fails", func() {
+		var disaster error
+
+		BeforeEach(func() {
+			disaster = errors.New("nope")
+			fakeRoundTripper.RoundTripStub = nil
+			fakeRoundTripper.RoundTripReturns(nil, disaster)
+		})
+
+		It("exports a record with the error", func() {
+			_, err := roundTripper.RoundTrip(newBodyRequest("some-request"))
+			Expect(err).To(Equal(disaster))
+
+			Expect(fakeExporter.ExportCallCount()).To(Equal(1))
+
+			record := fakeExporter.ExportArgsForCall(0)
+			Expect(record.WorkerName).To(Equal("some-worker"))
+			Expect(record.StatusCode).To(BeZero())
+			Expect(record.Err).To(Equal(disaster))
+		})
+	})
+})
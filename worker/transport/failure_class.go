@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"net"
+	"net/url"
+)
+
+// FailureClass categorizes a failed worker request by how it should be
+// handled.
+type FailureClass int
+
+const (
+	// FailureFatal is a failure that will not go away by sending the request
+	// again.
+	FailureFatal FailureClass = iota
+
+	// FailureRetryable is a failure reaching the worker, e.g. a refused or
+	// timed out connection, which may succeed when sent again.
+	FailureRetryable
+
+	// FailureCapacity is a failure caused by the worker, or the pool, not
+	// accepting more requests right now. The worker itself is not at fault and
+	// the request may be sent to another worker or again later.
+	FailureCapacity
+)
+
+func (class FailureClass) String() string {
+	switch class {
+	case FailureRetryable:
+		return "retryable"
+	case FailureCapacity:
+		return "capacity"
+	default:
+		return "fatal"
+	}
+}
+
+// ClassifyFailure returns the FailureClass of an error returned by a worker
+// request.
+func ClassifyFailure(err error) FailureClass {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+
+	switch err {
+	case ErrNoWorkersAvailable, ErrWorkerInMaintenance, ErrLoadShed, ErrWorkerEjected:
+		return FailureCapacity
+	case ErrRequestTimedOut:
+		return FailureRetryable
+	}
+
+	switch err := err.(type) {
+	case WorkerDrainingError:
+		return FailureCapacity
+	case WorkerUnreachableError:
+		return FailureRetryable
+	case *net.OpError:
+		return FailureRetryable
+	case net.Error:
+		if err.Timeout() {
+			return FailureRetryable
+		}
+	}
+
+	return FailureFatal
+}
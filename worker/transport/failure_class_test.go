@@ -0,0 +1,39 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClassifyFailure", func() {
+	It("classifies errors of workers not accepting requests as capacity failures", func() {
+		Expect(transport.ClassifyFailure(transport.ErrLoadShed)).To(Equal(transport.FailureCapacity))
+		Expect(transport.ClassifyFailure(transport.ErrNoWorkersAvailable)).To(Equal(transport.FailureCapacity))
+		Expect(transport.ClassifyFailure(transport.ErrWorkerInMaintenance)).To(Equal(transport.FailureCapacity))
+		Expect(transport.ClassifyFailure(transport.ErrWorkerEjected)).To(Equal(transport.FailureCapacity))
+		Expect(transport.ClassifyFailure(transport.WorkerDrainingError{WorkerName: "some-worker"})).To(Equal(transport.FailureCapacity))
+	})
+
+	It("classifies errors reaching a worker as retryable failures", func() {
+		connectionErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+		Expect(transport.ClassifyFailure(connectionErr)).To(Equal(transport.FailureRetryable))
+		Expect(transport.ClassifyFailure(&url.Error{Op: "Get", URL: "http://1.2.3.4", Err: connectionErr})).To(Equal(transport.FailureRetryable))
+		Expect(transport.ClassifyFailure(transport.ErrRequestTimedOut)).To(Equal(transport.FailureRetryable))
+		Expect(transport.ClassifyFailure(transport.WorkerUnreachableError{WorkerName: "some-worker", WorkerState: "stalled"})).To(Equal(transport.FailureRetryable))
+	})
+
+	It("classifies any other error as fatal", func() {
+		Expect(transport.ClassifyFailure(transport.WorkerMissingError{WorkerName: "some-worker"})).To(Equal(transport.FailureFatal))
+		Expect(transport.ClassifyFailure(context.Canceled)).To(Equal(transport.FailureFatal))
+		Expect(transport.ClassifyFailure(errors.New("some-error"))).To(Equal(transport.FailureFatal))
+	})
+})
@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"net/http"
+
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+// IdempotencyTokenHeader carries a token identifying a logical request, which
+// stays the same across its retries. Workers use it to recognize a retried
+// request that they have already acted upon.
+const IdempotencyTokenHeader = "Idempotency-Key"
+
+type idempotentRoundTripper struct {
+	innerRoundTripper http.RoundTripper
+}
+
+// NewIdempotentRoundTripper returns a http.RoundTripper which attaches an
+// idempotency token to every non-idempotent request (e.g. creating a
+// container) that does not carry one yet. It is meant to wrap a retrying
+// http.RoundTripper, so that all attempts of a request share the same token.
+func NewIdempotentRoundTripper(innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &idempotentRoundTripper{
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *idempotentRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !needsIdempotencyToken(request) {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	token, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set(IdempotencyTokenHeader, token.String())
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+
+	return c.innerRoundTripper.RoundTrip(&updatedRequest)
+}
+
+func needsIdempotencyToken(request *http.Request) bool {
+	if request.Header.Get(IdempotencyTokenHeader) != "" {
+		return false
+	}
+
+	return request.Method == "POST" || request.Method == "PATCH"
+}
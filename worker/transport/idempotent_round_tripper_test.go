@@ -0,0 +1,130 @@
+package transport_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IdempotentRoundTripper #RoundTrip", func() {
+	var (
+		fakeWorker   *fakeContainerWorker
+		server       *httptest.Server
+		roundTripper http.RoundTripper
+	)
+
+	BeforeEach(func() {
+		fakeWorker = &fakeContainerWorker{
+			containers: map[string]bool{},
+		}
+
+		server = httptest.NewServer(fakeWorker)
+
+		roundTripper = transport.NewIdempotentRoundTripper(&retryOnceRoundTripper{
+			innerRoundTripper: &http.Transport{DisableKeepAlives: true},
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	createContainer := func() {
+		request, err := http.NewRequest("POST", server.URL+"/containers", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		response.Body.Close()
+	}
+
+	Context("when the response to a create-container request is lost", func() {
+		BeforeEach(func() {
+			fakeWorker.dropNextResponse = true
+		})
+
+		It("retries it with the same token without creating a duplicate container", func() {
+			createContainer()
+
+			Expect(fakeWorker.requests).To(Equal(2))
+			Expect(fakeWorker.containers).To(HaveLen(1))
+		})
+	})
+
+	It("uses a different token for each request", func() {
+		createContainer()
+		createContainer()
+
+		Expect(fakeWorker.requests).To(Equal(2))
+		Expect(fakeWorker.containers).To(HaveLen(2))
+	})
+
+	It("does not attach a token to idempotent requests", func() {
+		request, err := http.NewRequest("GET", server.URL+"/containers", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+		response.Body.Close()
+
+		Expect(fakeWorker.lastToken).To(BeEmpty())
+	})
+})
+
+// fakeContainerWorker creates a container for every token it has not seen
+// before, and can drop the connection instead of responding.
+type fakeContainerWorker struct {
+	lock sync.Mutex
+
+	dropNextResponse bool
+	requests         int
+	lastToken        string
+	containers       map[string]bool
+}
+
+func (worker *fakeContainerWorker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	worker.requests++
+	worker.lastToken = r.Header.Get(transport.IdempotencyTokenHeader)
+
+	if r.Method == "POST" {
+		worker.containers[worker.lastToken] = true
+	}
+
+	if worker.dropNextResponse {
+		worker.dropNextResponse = false
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		Expect(err).NotTo(HaveOccurred())
+		conn.Close()
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type retryOnceRoundTripper struct {
+	innerRoundTripper http.RoundTripper
+}
+
+func (c *retryOnceRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err == nil {
+		return response, nil
+	}
+
+	if request.Body != nil {
+		return nil, errors.New("cannot retry request with a body")
+	}
+
+	return c.innerRoundTripper.RoundTrip(request)
+}
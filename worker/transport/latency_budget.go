@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// LatencyBudget is the total time a group of requests, e.g. those made by
+// the steps of a build, may spend waiting on workers.
+type LatencyBudget struct {
+	remainingL sync.Mutex
+	remaining  time.Duration
+}
+
+func NewLatencyBudget(total time.Duration) *LatencyBudget {
+	return &LatencyBudget{
+		remaining: total,
+	}
+}
+
+// Spend deducts the latency of a request from the budget.
+func (budget *LatencyBudget) Spend(latency time.Duration) {
+	budget.remainingL.Lock()
+	defer budget.remainingL.Unlock()
+
+	budget.remaining -= latency
+}
+
+// Remaining returns how much of the budget is left. It is never negative.
+func (budget *LatencyBudget) Remaining() time.Duration {
+	budget.remainingL.Lock()
+	defer budget.remainingL.Unlock()
+
+	if budget.remaining < 0 {
+		return 0
+	}
+
+	return budget.remaining
+}
+
+type latencyBudgetKey struct{}
+
+// WithLatencyBudget returns a copy of ctx which makes requests sent with it
+// share the given budget.
+func WithLatencyBudget(ctx context.Context, budget *LatencyBudget) context.Context {
+	return context.WithValue(ctx, latencyBudgetKey{}, budget)
+}
+
+// LatencyBudgetFromContext returns the budget carried by ctx, if any.
+func LatencyBudgetFromContext(ctx context.Context) (*LatencyBudget, bool) {
+	budget, found := ctx.Value(latencyBudgetKey{}).(*LatencyBudget)
+	return budget, found
+}
+
+type latencyBudgetRoundTripper struct {
+	clock             clock.Clock
+	innerRoundTripper http.RoundTripper
+}
+
+// NewLatencyBudgetRoundTripper returns a http.RoundTripper which deducts the
+// time each request waits for its response from the latency budget carried
+// by the request's context. Once the budget is spent, requests fail with
+// ErrLatencyBudgetSpent without being sent.
+func NewLatencyBudgetRoundTripper(clock clock.Clock, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &latencyBudgetRoundTripper{
+		clock:             clock,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *latencyBudgetRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	budget, found := LatencyBudgetFromContext(request.Context())
+	if !found {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	if budget.Remaining() == 0 {
+		return nil, ErrLatencyBudgetSpent
+	}
+
+	started := c.clock.Now()
+
+	response, err := c.innerRoundTripper.RoundTrip(request)
+
+	budget.Spend(c.clock.Since(started))
+
+	return response, err
+}
@@ -0,0 +1,83 @@
+package transport_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LatencyBudgetRoundTripper #RoundTrip", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		request *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			fakeClock.Increment(4 * time.Second)
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+			}, nil
+		}
+
+		roundTripper = transport.NewLatencyBudgetRoundTripper(fakeClock, fakeRoundTripper)
+
+		request = newBodyRequest("")
+	})
+
+	Context("when the request carries no budget", func() {
+		It("sends it", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when requests share a budget", func() {
+		var budget *transport.LatencyBudget
+
+		BeforeEach(func() {
+			budget = transport.NewLatencyBudget(10 * time.Second)
+			request = request.WithContext(transport.WithLatencyBudget(context.Background(), budget))
+		})
+
+		It("deducts the latency of each request from it", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(budget.Remaining()).To(Equal(6 * time.Second))
+
+			_, err = roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(budget.Remaining()).To(Equal(2 * time.Second))
+		})
+
+		It("fails requests fast once the budget is spent", func() {
+			for i := 0; i < 3; i++ {
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(budget.Remaining()).To(BeZero())
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).To(Equal(transport.ErrLatencyBudgetSpent))
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(3))
+		})
+	})
+})
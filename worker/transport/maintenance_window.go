@@ -0,0 +1,14 @@
+package transport
+
+import "time"
+
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains returns true if t is within the window. The window includes its
+// start but not its end.
+func (window MaintenanceWindow) Contains(t time.Time) bool {
+	return !t.Before(window.Start) && t.Before(window.End)
+}
@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// outlierSamples is the number of recent latencies averaged per worker.
+const outlierSamples = 10
+
+// OutlierDetector ejects workers whose average latency is more than a given
+// factor above the median average latency of their peers. An ejected worker
+// is re-admitted with a clean slate once the cooldown has passed.
+type OutlierDetector struct {
+	clock    clock.Clock
+	factor   float64
+	cooldown time.Duration
+
+	workersL     sync.Mutex
+	latencies    map[string][]time.Duration
+	ejectedUntil map[string]time.Time
+}
+
+func NewOutlierDetector(clock clock.Clock, factor float64, cooldown time.Duration) *OutlierDetector {
+	return &OutlierDetector{
+		clock:    clock,
+		factor:   factor,
+		cooldown: cooldown,
+
+		latencies:    map[string][]time.Duration{},
+		ejectedUntil: map[string]time.Time{},
+	}
+}
+
+func (detector *OutlierDetector) Record(workerName string, latency time.Duration) {
+	detector.workersL.Lock()
+	defer detector.workersL.Unlock()
+
+	latencies := append(detector.latencies[workerName], latency)
+	if len(latencies) > outlierSamples {
+		latencies = latencies[len(latencies)-outlierSamples:]
+	}
+
+	detector.latencies[workerName] = latencies
+
+	peerMedian, found := detector.peerMedian(workerName)
+	if !found {
+		return
+	}
+
+	if float64(average(latencies)) > detector.factor*float64(peerMedian) {
+		detector.ejectedUntil[workerName] = detector.clock.Now().Add(detector.cooldown)
+		delete(detector.latencies, workerName)
+	}
+}
+
+func (detector *OutlierDetector) IsEjected(workerName string) bool {
+	detector.workersL.Lock()
+	defer detector.workersL.Unlock()
+
+	until, found := detector.ejectedUntil[workerName]
+	if !found {
+		return false
+	}
+
+	if !detector.clock.Now().Before(until) {
+		delete(detector.ejectedUntil, workerName)
+		return false
+	}
+
+	return true
+}
+
+// peerMedian must be called with workersL held.
+func (detector *OutlierDetector) peerMedian(workerName string) (time.Duration, bool) {
+	averages := []time.Duration{}
+	for peer, latencies := range detector.latencies {
+		if peer == workerName || len(latencies) == 0 {
+			continue
+		}
+
+		averages = append(averages, average(latencies))
+	}
+
+	if len(averages) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(averages, func(i, j int) bool {
+		return averages[i] < averages[j]
+	})
+
+	return averages[len(averages)/2], true
+}
+
+func average(latencies []time.Duration) time.Duration {
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	return total / time.Duration(len(latencies))
+}
@@ -0,0 +1,67 @@
+package transport_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OutlierDetector", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		detector  *transport.OutlierDetector
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		detector = transport.NewOutlierDetector(fakeClock, 3, time.Minute)
+
+		for i := 0; i < 5; i++ {
+			detector.Record("worker-a", 100*time.Millisecond)
+			detector.Record("worker-b", 120*time.Millisecond)
+			detector.Record("worker-c", 90*time.Millisecond)
+		}
+	})
+
+	It("does not eject workers with latency comparable to their peers", func() {
+		Expect(detector.IsEjected("worker-a")).To(BeFalse())
+		Expect(detector.IsEjected("worker-b")).To(BeFalse())
+		Expect(detector.IsEjected("worker-c")).To(BeFalse())
+	})
+
+	Context("when a worker's latency deviates significantly from its peers", func() {
+		BeforeEach(func() {
+			for i := 0; i < 5; i++ {
+				detector.Record("worker-slow", time.Second)
+			}
+		})
+
+		It("ejects it", func() {
+			Expect(detector.IsEjected("worker-slow")).To(BeTrue())
+			Expect(detector.IsEjected("worker-a")).To(BeFalse())
+		})
+
+		It("re-admits it after the cooldown", func() {
+			fakeClock.Increment(59 * time.Second)
+			Expect(detector.IsEjected("worker-slow")).To(BeTrue())
+
+			fakeClock.Increment(time.Second)
+			Expect(detector.IsEjected("worker-slow")).To(BeFalse())
+		})
+	})
+
+	Context("when a worker has no peers", func() {
+		BeforeEach(func() {
+			detector = transport.NewOutlierDetector(fakeClock, 3, time.Minute)
+			detector.Record("lonely-worker", time.Hour)
+		})
+
+		It("never ejects it", func() {
+			Expect(detector.IsEjected("lonely-worker")).To(BeFalse())
+		})
+	})
+})
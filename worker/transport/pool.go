@@ -0,0 +1,589 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/concourse/atc/metric"
+)
+
+// ErrorRateWindow is how far back a worker's failed requests count towards
+// its error rate.
+const ErrorRateWindow = 5 * time.Minute
+
+// Pool keeps track of the requests in flight to each worker so that routing
+// can take the worker's state into account, e.g. while it is being drained.
+type Pool struct {
+	logger     lager.Logger
+	clock      clock.Clock
+	config     PoolConfig
+	errorRates *ErrorRateTracker
+	outliers   *OutlierDetector
+
+	workersL sync.Mutex
+	workers  map[string]*workerConnections
+	inFlight int
+
+	tunnelsL sync.Mutex
+	tunnels  map[string]*http.Transport
+}
+
+type PoolConfig struct {
+	// MaxConnectionsPerWorker caps the number of requests in flight to a
+	// single worker. Requests beyond the cap wait in the worker's queue until
+	// an in-flight request finishes, and are sent in order of their priority.
+	// With TeamPools, each team has its own cap and queue. Zero means no cap.
+	MaxConnectionsPerWorker int
+
+	// QueueAgingInterval is how long a queued request waits before it gains
+	// a priority level.
+	QueueAgingInterval time.Duration
+
+	// OutlierLatencyFactor ejects a worker from routing once its average
+	// latency exceeds the median of its peers by this factor. It is
+	// re-admitted after OutlierCooldown. Zero disables outlier detection.
+	OutlierLatencyFactor float64
+	OutlierCooldown      time.Duration
+
+	// LoadSheddingThreshold is the number of requests in flight across all
+	// workers above which requests with a priority below PriorityNormal are
+	// rejected with ErrLoadShed. Zero disables load shedding.
+	LoadSheddingThreshold int
+}
+
+type workerConnections struct {
+	draining bool
+	drained  chan struct{}
+
+	maintenance *MaintenanceWindow
+
+	nextID int
+	active map[int]context.CancelFunc
+
+	// slots are kept per team, so that each team has its own connection cap
+	// and queue for the worker; the requests without a team share one
+	slots map[string]*connectionSlots
+}
+
+type connectionSlots struct {
+	inUse    int
+	queue    *RequestQueue
+	waiters  map[*QueuedRequest]chan struct{}
+	reserved int
+}
+
+func NewPool(logger lager.Logger, clock clock.Clock, config PoolConfig) *Pool {
+	return &Pool{
+		logger:     logger,
+		clock:      clock,
+		config:     config,
+		errorRates: NewErrorRateTracker(clock, ErrorRateWindow),
+		outliers:   NewOutlierDetector(clock, config.OutlierLatencyFactor, config.OutlierCooldown),
+		workers:    map[string]*workerConnections{},
+		tunnels:    map[string]*http.Transport{},
+	}
+}
+
+// RoundTripper returns a http.RoundTripper which routes requests for the named
+// worker through the pool. A request stays in flight until its response body
+// is closed.
+func (pool *Pool) RoundTripper(workerName string, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return pool.teamRoundTripper("", workerName, innerRoundTripper)
+}
+
+func (pool *Pool) teamRoundTripper(teamName string, workerName string, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &pooledRoundTripper{
+		pool:              pool,
+		teamName:          teamName,
+		workerName:        workerName,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+// TunnelRoundTripper returns a http.RoundTripper which sends requests through
+// the tunnel listening at tunnelURL, e.g. for workers registered through a
+// reverse tunnel. All requests sent through the same tunnel share its
+// connections rather than each worker dialing its own.
+func (pool *Pool) TunnelRoundTripper(tunnelURL *url.URL) http.RoundTripper {
+	pool.tunnelsL.Lock()
+	defer pool.tunnelsL.Unlock()
+
+	key := tunnelURL.String()
+
+	tunnel, found := pool.tunnels[key]
+	if !found {
+		tunnel = &http.Transport{
+			Proxy: http.ProxyURL(tunnelURL),
+		}
+
+		pool.tunnels[key] = tunnel
+	}
+
+	return tunnel
+}
+
+// ProbeTunnels sends a probe through each tunnel, reusing one of its idle
+// connections if there are any. If a tunnel does not respond within the
+// timeout, its idle connections are closed so that the next request dials a
+// new connection rather than reusing a dead one.
+func (pool *Pool) ProbeTunnels(timeout time.Duration) {
+	pool.tunnelsL.Lock()
+
+	tunnels := map[string]*http.Transport{}
+	for tunnelURL, tunnel := range pool.tunnels {
+		tunnels[tunnelURL] = tunnel
+	}
+
+	pool.tunnelsL.Unlock()
+
+	for tunnelURL, tunnel := range tunnels {
+		if !pool.probe(tunnelURL, tunnel, timeout) {
+			tunnel.CloseIdleConnections()
+		}
+	}
+}
+
+func (pool *Pool) probe(tunnelURL string, tunnel *http.Transport, timeout time.Duration) bool {
+	request, err := http.NewRequest("HEAD", tunnelURL, nil)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timer := pool.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	go func() {
+		select {
+		case <-timer.C():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	response, err := tunnel.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+
+	response.Body.Close()
+
+	return true
+}
+
+// SelectWorker picks the worker to route a request to out of the given
+// candidates, preferring the worker with the lowest recent error rate. Workers
+// that can not be resolved, or that are excluded by the options, are never
+// selected.
+func (pool *Pool) SelectWorker(candidates []string, options ...RequestOption) (string, error) {
+	opts := newRequestOptions(options)
+
+	var selected string
+	var lowestErrorRate float64
+
+	found := false
+	for _, workerName := range candidates {
+		if opts.excluded[workerName] {
+			continue
+		}
+
+		if pool.ResolveWorker(workerName) != nil {
+			continue
+		}
+
+		errorRate := pool.errorRates.ErrorRate(workerName)
+		if !found || errorRate < lowestErrorRate {
+			selected = workerName
+			lowestErrorRate = errorRate
+			found = true
+		}
+	}
+
+	if !found {
+		return "", ErrNoWorkersAvailable
+	}
+
+	return selected, nil
+}
+
+// Placement is the worker chosen by SelectWorkerWithFallback.
+type Placement struct {
+	WorkerName string
+
+	// Degraded is set when none of the preferred workers was available and
+	// the request was placed on a fallback worker instead.
+	Degraded bool
+}
+
+// SelectWorkerWithFallback picks a worker out of the preferred candidates
+// like SelectWorker. If none of them is available it degrades to any of the
+// fallback candidates rather than failing, flags the placement as degraded
+// and logs it.
+func (pool *Pool) SelectWorkerWithFallback(preferred []string, fallback []string, options ...RequestOption) (Placement, error) {
+	workerName, reason := pool.SelectWorker(preferred, options...)
+	if reason == nil {
+		return Placement{WorkerName: workerName}, nil
+	}
+
+	workerName, err := pool.SelectWorker(fallback, options...)
+	if err != nil {
+		return Placement{}, err
+	}
+
+	pool.logger.Info("degraded-placement", lager.Data{
+		"worker":    workerName,
+		"preferred": preferred,
+		"reason":    reason.Error(),
+	})
+
+	return Placement{WorkerName: workerName, Degraded: true}, nil
+}
+
+// ResolveWorker returns an error if requests can not currently be routed to
+// the named worker, e.g. because it is being drained, is inside its
+// maintenance window or has been ejected as a latency outlier.
+func (pool *Pool) ResolveWorker(workerName string) error {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	return pool.resolve(workerName, pool.connections(workerName))
+}
+
+// SetMaintenanceWindow excludes the named worker from routing for the
+// duration of the window. It replaces any window set before.
+func (pool *Pool) SetMaintenanceWindow(workerName string, window MaintenanceWindow) {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	pool.connections(workerName).maintenance = &window
+}
+
+// QueueLength returns the number of requests waiting for a connection to the
+// named worker.
+func (pool *Pool) QueueLength(workerName string) int {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	length := 0
+	for _, slots := range pool.connections(workerName).slots {
+		length += slots.queue.Len()
+	}
+
+	return length
+}
+
+func (pool *Pool) teamQueueLength(teamName string, workerName string) int {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	return pool.slots(pool.connections(workerName), teamName).queue.Len()
+}
+
+// DrainWorker stops routing new requests to the named worker and waits for
+// the requests already in flight to finish. Any request still in flight once
+// the grace period has elapsed is forcibly closed. The number of requests
+// still in flight is emitted as a metric until the worker is drained.
+func (pool *Pool) DrainWorker(workerName string, grace time.Duration) {
+	pool.workersL.Lock()
+
+	conns := pool.connections(workerName)
+	if !conns.draining {
+		conns.draining = true
+		conns.drained = make(chan struct{})
+	}
+
+	metric.WorkerDrainingConnections{
+		WorkerName:  workerName,
+		Connections: len(conns.active),
+	}.Emit(pool.logger)
+
+	if len(conns.active) == 0 {
+		pool.workersL.Unlock()
+		return
+	}
+
+	drained := conns.drained
+
+	pool.workersL.Unlock()
+
+	timer := pool.clock.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+	case <-timer.C():
+		pool.workersL.Lock()
+		for _, cancel := range conns.active {
+			cancel()
+		}
+		pool.workersL.Unlock()
+	}
+}
+
+func (pool *Pool) acquire(ctx context.Context, teamName string, workerName string) (*pooledConnection, error) {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	conns := pool.connections(workerName)
+	slots := pool.slots(conns, teamName)
+
+	err := pool.resolve(workerName, conns)
+	if err != nil {
+		return nil, err
+	}
+
+	if pool.shouldShed(PriorityFromContext(ctx)) {
+		return nil, ErrLoadShed
+	}
+
+	if pool.atCapacity(slots) {
+		err := pool.wait(ctx, slots, PriorityFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		err = pool.resolve(workerName, conns)
+		if err != nil {
+			pool.dispatch(slots)
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	id := conns.nextID
+	conns.nextID++
+	conns.active[id] = cancel
+	slots.inUse++
+	pool.inFlight++
+
+	return &pooledConnection{
+		pool:       pool,
+		teamName:   teamName,
+		workerName: workerName,
+		id:         id,
+		ctx:        ctx,
+	}, nil
+}
+
+// wait queues the caller until a connection to the worker is handed to it by
+// dispatch, which hands connections to higher priority requests first. It
+// must be called with workersL held, which it releases while waiting.
+func (pool *Pool) wait(ctx context.Context, slots *connectionSlots, priority Priority) error {
+	queued := slots.queue.Push(priority)
+
+	ready := make(chan struct{})
+	slots.waiters[queued] = ready
+
+	pool.workersL.Unlock()
+
+	select {
+	case <-ready:
+		pool.workersL.Lock()
+	case <-ctx.Done():
+		pool.workersL.Lock()
+
+		if _, stillWaiting := slots.waiters[queued]; stillWaiting {
+			slots.queue.Remove(queued)
+			delete(slots.waiters, queued)
+			return ctx.Err()
+		}
+
+		// a connection was handed over while giving up; pass it on
+		slots.reserved--
+		pool.dispatch(slots)
+		return ctx.Err()
+	}
+
+	slots.reserved--
+
+	return nil
+}
+
+// dispatch hands free connections to queued requests. It must be called with
+// workersL held.
+func (pool *Pool) dispatch(slots *connectionSlots) {
+	for !pool.atCapacity(slots) {
+		queued, found := slots.queue.Pop()
+		if !found {
+			return
+		}
+
+		slots.reserved++
+
+		close(slots.waiters[queued])
+		delete(slots.waiters, queued)
+	}
+}
+
+// shouldShed must be called with workersL held.
+func (pool *Pool) shouldShed(priority Priority) bool {
+	threshold := pool.config.LoadSheddingThreshold
+	return threshold > 0 && pool.inFlight >= threshold && priority < PriorityNormal
+}
+
+// atCapacity must be called with workersL held.
+func (pool *Pool) atCapacity(slots *connectionSlots) bool {
+	max := pool.config.MaxConnectionsPerWorker
+	return max > 0 && slots.inUse+slots.reserved >= max
+}
+
+func (pool *Pool) release(conn *pooledConnection) {
+	pool.workersL.Lock()
+	defer pool.workersL.Unlock()
+
+	conns := pool.connections(conn.workerName)
+
+	cancel, found := conns.active[conn.id]
+	if !found {
+		return
+	}
+
+	cancel()
+	delete(conns.active, conn.id)
+	pool.inFlight--
+
+	slots := pool.slots(conns, conn.teamName)
+	slots.inUse--
+
+	pool.dispatch(slots)
+
+	if conns.draining {
+		metric.WorkerDrainingConnections{
+			WorkerName:  conn.workerName,
+			Connections: len(conns.active),
+		}.Emit(pool.logger)
+
+		if len(conns.active) == 0 {
+			close(conns.drained)
+		}
+	}
+}
+
+// resolve must be called with workersL held.
+func (pool *Pool) resolve(workerName string, conns *workerConnections) error {
+	if conns.draining {
+		return WorkerDrainingError{WorkerName: workerName}
+	}
+
+	if conns.maintenance != nil && conns.maintenance.Contains(pool.clock.Now()) {
+		return ErrWorkerInMaintenance
+	}
+
+	if pool.config.OutlierLatencyFactor > 0 && pool.outliers.IsEjected(workerName) {
+		return ErrWorkerEjected
+	}
+
+	return nil
+}
+
+// connections must be called with workersL held.
+func (pool *Pool) connections(workerName string) *workerConnections {
+	conns, found := pool.workers[workerName]
+	if !found {
+		conns = &workerConnections{
+			active: map[int]context.CancelFunc{},
+			slots:  map[string]*connectionSlots{},
+		}
+
+		pool.workers[workerName] = conns
+	}
+
+	return conns
+}
+
+// slots must be called with workersL held.
+func (pool *Pool) slots(conns *workerConnections, teamName string) *connectionSlots {
+	slots, found := conns.slots[teamName]
+	if !found {
+		slots = &connectionSlots{
+			queue:   NewRequestQueue(pool.clock, pool.config.QueueAgingInterval),
+			waiters: map[*QueuedRequest]chan struct{}{},
+		}
+
+		conns.slots[teamName] = slots
+	}
+
+	return slots
+}
+
+type pooledConnection struct {
+	pool       *Pool
+	teamName   string
+	workerName string
+	id         int
+	ctx        context.Context
+
+	releaseOnce sync.Once
+}
+
+func (conn *pooledConnection) release() {
+	conn.releaseOnce.Do(func() {
+		conn.pool.release(conn)
+	})
+}
+
+type pooledRoundTripper struct {
+	pool              *Pool
+	teamName          string
+	workerName        string
+	innerRoundTripper http.RoundTripper
+}
+
+func (c *pooledRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	conn, err := c.pool.acquire(request.Context(), c.teamName, c.workerName)
+	if err != nil {
+		return nil, err
+	}
+
+	started := c.pool.clock.Now()
+
+	response, err := c.innerRoundTripper.RoundTrip(request.WithContext(conn.ctx))
+
+	// a request cancelled by its caller, e.g. a speculative request losing the
+	// race, or force-closed by draining the worker says nothing about the worker
+	if conn.ctx.Err() == nil {
+		failed := err != nil || response.StatusCode >= http.StatusInternalServerError
+		if err != nil && ClassifyFailure(err) == FailureCapacity {
+			// a worker turning requests away for lack of capacity is not faulty
+			failed = false
+		}
+
+		c.pool.errorRates.Record(c.workerName, failed)
+
+		if err == nil && c.pool.config.OutlierLatencyFactor > 0 {
+			c.pool.outliers.Record(c.workerName, c.pool.clock.Since(started))
+		}
+	}
+
+	if err != nil {
+		conn.release()
+		return nil, err
+	}
+
+	response.Body = &pooledBody{
+		ReadCloser: response.Body,
+		conn:       conn,
+	}
+
+	return response, nil
+}
+
+type pooledBody struct {
+	io.ReadCloser
+
+	conn *pooledConnection
+}
+
+func (body *pooledBody) Close() error {
+	defer body.conn.release()
+	return body.ReadCloser.Close()
+}
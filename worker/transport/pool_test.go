@@ -0,0 +1,803 @@
+package transport_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/onsi/gomega/types"
+)
+
+var _ = Describe("Pool", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		pool             *transport.Pool
+		roundTripper     http.RoundTripper
+		request          *http.Request
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+			}, nil
+		}
+
+		pool = transport.NewPool(logger, fakeClock, transport.PoolConfig{})
+		roundTripper = pool.RoundTripper("some-worker", fakeRoundTripper)
+
+		requestURL, err := url.Parse("http://1.2.3.4/something")
+		Expect(err).NotTo(HaveOccurred())
+
+		request = &http.Request{
+			URL: requestURL,
+		}
+	})
+
+	Describe("SelectWorker", func() {
+		var (
+			unhealthyRoundTripper http.RoundTripper
+			healthyRoundTripper   http.RoundTripper
+		)
+
+		BeforeEach(func() {
+			unhealthyRoundTripper = pool.RoundTripper("unhealthy-worker", fakeRoundTripper)
+			healthyRoundTripper = pool.RoundTripper("healthy-worker", fakeRoundTripper)
+
+			fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+				status := http.StatusOK
+				if request.URL.Path == "/fail" {
+					status = http.StatusInternalServerError
+				}
+
+				return &http.Response{
+					StatusCode: status,
+					Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+				}, nil
+			}
+
+			failingURL, err := url.Parse("http://1.2.3.4/fail")
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 3; i++ {
+				response, err := unhealthyRoundTripper.RoundTrip(&http.Request{URL: failingURL})
+				Expect(err).NotTo(HaveOccurred())
+				response.Body.Close()
+
+				response, err = healthyRoundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+				response.Body.Close()
+			}
+		})
+
+		It("deprioritizes workers with a high recent error rate", func() {
+			Expect(pool.SelectWorker([]string{"unhealthy-worker", "healthy-worker"})).To(Equal("healthy-worker"))
+			Expect(pool.SelectWorker([]string{"healthy-worker", "unhealthy-worker"})).To(Equal("healthy-worker"))
+		})
+
+		It("selects an error-prone worker when it is the only candidate", func() {
+			Expect(pool.SelectWorker([]string{"unhealthy-worker"})).To(Equal("unhealthy-worker"))
+		})
+
+		Context("once the errors are older than the error rate window", func() {
+			BeforeEach(func() {
+				fakeClock.Increment(transport.ErrorRateWindow)
+			})
+
+			It("no longer deprioritizes the worker", func() {
+				Expect(pool.SelectWorker([]string{"unhealthy-worker", "healthy-worker"})).To(Equal("unhealthy-worker"))
+			})
+		})
+
+		Context("when a worker is excluded for the request", func() {
+			It("is never selected", func() {
+				Expect(pool.SelectWorker(
+					[]string{"unhealthy-worker", "healthy-worker"},
+					transport.Exclude("healthy-worker"),
+				)).To(Equal("unhealthy-worker"))
+			})
+
+			It("is only excluded for that request", func() {
+				_, err := pool.SelectWorker([]string{"healthy-worker"}, transport.Exclude("healthy-worker"))
+				Expect(err).To(Equal(transport.ErrNoWorkersAvailable))
+
+				Expect(pool.SelectWorker([]string{"healthy-worker"})).To(Equal("healthy-worker"))
+			})
+
+			It("can exclude more than one worker", func() {
+				_, err := pool.SelectWorker(
+					[]string{"unhealthy-worker", "healthy-worker"},
+					transport.Exclude("healthy-worker"),
+					transport.Exclude("unhealthy-worker"),
+				)
+				Expect(err).To(Equal(transport.ErrNoWorkersAvailable))
+			})
+		})
+
+		Context("when a worker turns requests away for lack of capacity", func() {
+			BeforeEach(func() {
+				busyRoundTripper := new(transportfakes.FakeRoundTripper)
+				busyRoundTripper.RoundTripReturns(nil, transport.ErrLoadShed)
+
+				for i := 0; i < 3; i++ {
+					_, err := pool.RoundTripper("busy-worker", busyRoundTripper).RoundTrip(request)
+					Expect(err).To(Equal(transport.ErrLoadShed))
+				}
+			})
+
+			It("does not count against its error rate", func() {
+				Expect(pool.SelectWorker([]string{"busy-worker", "healthy-worker"})).To(Equal("busy-worker"))
+			})
+		})
+
+		Context("when a request to a worker is cancelled", func() {
+			BeforeEach(func() {
+				var cancelInFlight context.CancelFunc
+
+				cancelledRoundTripper := new(transportfakes.FakeRoundTripper)
+				cancelledRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+					// the caller gives up while the request is in flight
+					cancelInFlight()
+					return nil, request.Context().Err()
+				}
+
+				for i := 0; i < 3; i++ {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+
+					cancelInFlight = cancel
+
+					_, err := pool.RoundTripper("cancelled-worker", cancelledRoundTripper).RoundTrip(request.WithContext(ctx))
+					Expect(err).To(Equal(context.Canceled))
+				}
+			})
+
+			It("does not count against its error rate", func() {
+				Expect(pool.SelectWorker([]string{"cancelled-worker", "healthy-worker"})).To(Equal("cancelled-worker"))
+			})
+		})
+
+		Context("when a worker is being drained", func() {
+			BeforeEach(func() {
+				pool.DrainWorker("healthy-worker", time.Minute)
+			})
+
+			It("is never selected", func() {
+				Expect(pool.SelectWorker([]string{"unhealthy-worker", "healthy-worker"})).To(Equal("unhealthy-worker"))
+			})
+		})
+
+		Context("when no candidate can be selected", func() {
+			It("returns ErrNoWorkersAvailable", func() {
+				_, err := pool.SelectWorker([]string{})
+				Expect(err).To(Equal(transport.ErrNoWorkersAvailable))
+			})
+		})
+	})
+
+	Describe("SelectWorkerWithFallback", func() {
+		Context("when a preferred worker is available", func() {
+			It("places the request on it", func() {
+				placement, err := pool.SelectWorkerWithFallback([]string{"preferred-worker"}, []string{"fallback-worker"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(placement).To(Equal(transport.Placement{WorkerName: "preferred-worker"}))
+			})
+		})
+
+		Context("when none of the preferred workers are available", func() {
+			BeforeEach(func() {
+				pool.DrainWorker("preferred-worker", time.Minute)
+				pool.DrainWorker("other-preferred-worker", time.Minute)
+			})
+
+			It("degrades to a fallback worker", func() {
+				placement, err := pool.SelectWorkerWithFallback(
+					[]string{"preferred-worker", "other-preferred-worker"},
+					[]string{"preferred-worker", "fallback-worker"},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(placement).To(Equal(transport.Placement{
+					WorkerName: "fallback-worker",
+					Degraded:   true,
+				}))
+			})
+
+			It("logs the degraded placement", func() {
+				_, err := pool.SelectWorkerWithFallback([]string{"preferred-worker"}, []string{"fallback-worker"})
+				Expect(err).NotTo(HaveOccurred())
+
+				logs := logger.Logs()
+				Expect(logs).To(HaveLen(1))
+				Expect(logs[0].Message).To(Equal("test.degraded-placement"))
+				Expect(logs[0].Data).To(HaveKeyWithValue("worker", "fallback-worker"))
+				Expect(logs[0].Data).To(HaveKeyWithValue("reason", transport.ErrNoWorkersAvailable.Error()))
+			})
+
+			Context("when the fallback worker is excluded for the request", func() {
+				It("returns ErrNoWorkersAvailable", func() {
+					_, err := pool.SelectWorkerWithFallback(
+						[]string{"preferred-worker"},
+						[]string{"fallback-worker"},
+						transport.Exclude("fallback-worker"),
+					)
+					Expect(err).To(Equal(transport.ErrNoWorkersAvailable))
+				})
+			})
+
+			Context("when no fallback worker is available either", func() {
+				It("returns ErrNoWorkersAvailable without logging a degraded placement", func() {
+					_, err := pool.SelectWorkerWithFallback([]string{"preferred-worker"}, []string{"other-preferred-worker"})
+					Expect(err).To(Equal(transport.ErrNoWorkersAvailable))
+					Expect(logger.Logs()).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("ResolveWorker", func() {
+		It("resolves a worker without a maintenance window", func() {
+			Expect(pool.ResolveWorker("some-worker")).To(Succeed())
+		})
+
+		Context("when the worker has a maintenance window", func() {
+			BeforeEach(func() {
+				pool.SetMaintenanceWindow("some-worker", transport.MaintenanceWindow{
+					Start: fakeClock.Now().Add(time.Hour),
+					End:   fakeClock.Now().Add(2 * time.Hour),
+				})
+			})
+
+			It("is available before the window", func() {
+				Expect(pool.ResolveWorker("some-worker")).To(Succeed())
+				Expect(pool.SelectWorker([]string{"some-worker"})).To(Equal("some-worker"))
+			})
+
+			Context("inside the window", func() {
+				BeforeEach(func() {
+					fakeClock.Increment(time.Hour)
+				})
+
+				It("returns ErrWorkerInMaintenance", func() {
+					Expect(pool.ResolveWorker("some-worker")).To(Equal(transport.ErrWorkerInMaintenance))
+				})
+
+				It("excludes the worker from selection", func() {
+					Expect(pool.SelectWorker([]string{"some-worker", "some-other-worker"})).To(Equal("some-other-worker"))
+				})
+
+				It("does not route requests to it", func() {
+					_, err := roundTripper.RoundTrip(request)
+					Expect(err).To(Equal(transport.ErrWorkerInMaintenance))
+					Expect(fakeRoundTripper.RoundTripCallCount()).To(BeZero())
+				})
+			})
+
+			Context("after the window", func() {
+				BeforeEach(func() {
+					fakeClock.Increment(2 * time.Hour)
+				})
+
+				It("is available again", func() {
+					Expect(pool.ResolveWorker("some-worker")).To(Succeed())
+
+					_, err := roundTripper.RoundTrip(request)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when the worker is being drained", func() {
+			BeforeEach(func() {
+				pool.DrainWorker("some-worker", time.Minute)
+			})
+
+			It("returns WorkerDrainingError", func() {
+				Expect(pool.ResolveWorker("some-worker")).To(Equal(transport.WorkerDrainingError{WorkerName: "some-worker"}))
+			})
+		})
+	})
+
+	Describe("MaxConnectionsPerWorker", func() {
+		var responses []*http.Response
+
+		BeforeEach(func() {
+			pool = transport.NewPool(logger, fakeClock, transport.PoolConfig{
+				MaxConnectionsPerWorker: 2,
+			})
+			roundTripper = pool.RoundTripper("some-worker", fakeRoundTripper)
+
+			responses = nil
+			for i := 0; i < 2; i++ {
+				response, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				responses = append(responses, response)
+			}
+		})
+
+		AfterEach(func() {
+			for _, response := range responses {
+				response.Body.Close()
+			}
+		})
+
+		It("queues requests beyond the cap until an in-flight one completes", func() {
+			queued := make(chan *http.Response)
+			go func() {
+				defer GinkgoRecover()
+
+				response, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				queued <- response
+			}()
+
+			Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(2))
+
+			Expect(responses[0].Body.Close()).To(Succeed())
+
+			var response *http.Response
+			Eventually(queued).Should(Receive(&response))
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(3))
+
+			responses = append(responses, response)
+		})
+
+		It("proceeds with queued requests in the order they arrived", func() {
+			sent := make(chan string, 2)
+			for i, path := range []string{"/first", "/second"} {
+				queuedURL, err := url.Parse("http://1.2.3.4" + path)
+				Expect(err).NotTo(HaveOccurred())
+
+				go func() {
+					defer GinkgoRecover()
+
+					response, err := roundTripper.RoundTrip(&http.Request{URL: queuedURL})
+					Expect(err).NotTo(HaveOccurred())
+					response.Body.Close()
+
+					sent <- queuedURL.Path
+				}()
+
+				Eventually(func() int {
+					return pool.QueueLength("some-worker")
+				}).Should(Equal(i + 1))
+			}
+
+			Expect(responses[0].Body.Close()).To(Succeed())
+			Eventually(sent).Should(Receive(Equal("/first")))
+			Eventually(sent).Should(Receive(Equal("/second")))
+		})
+
+		It("hands free connections to higher priority requests first", func() {
+			sent := make(chan string, 2)
+			for i, queuedRequest := range []struct {
+				path     string
+				priority transport.Priority
+			}{
+				{"/low", transport.PriorityLow},
+				{"/high", transport.PriorityHigh},
+			} {
+				queuedURL, err := url.Parse("http://1.2.3.4" + queuedRequest.path)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := transport.WithPriority(context.Background(), queuedRequest.priority)
+
+				go func() {
+					defer GinkgoRecover()
+
+					response, err := roundTripper.RoundTrip((&http.Request{URL: queuedURL}).WithContext(ctx))
+					Expect(err).NotTo(HaveOccurred())
+
+					sent <- queuedURL.Path
+
+					response.Body.Close()
+				}()
+
+				Eventually(func() int {
+					return pool.QueueLength("some-worker")
+				}).Should(Equal(i + 1))
+			}
+
+			Expect(responses[0].Body.Close()).To(Succeed())
+			Eventually(sent).Should(Receive(Equal("/high")))
+
+			Expect(responses[1].Body.Close()).To(Succeed())
+			Eventually(sent).Should(Receive(Equal("/low")))
+		})
+
+		It("does not cap requests to other workers", func() {
+			otherRoundTripper := pool.RoundTripper("some-other-worker", fakeRoundTripper)
+
+			response, err := otherRoundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			responses = append(responses, response)
+		})
+
+		Context("when a queued request is cancelled", func() {
+			It("returns the context's error and leaves the slot to the next request", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+
+				errs := make(chan error)
+				go func() {
+					_, err := roundTripper.RoundTrip(request.WithContext(ctx))
+					errs <- err
+				}()
+
+				Consistently(errs).ShouldNot(Receive())
+
+				cancel()
+				Eventually(errs).Should(Receive(Equal(context.Canceled)))
+
+				Expect(responses[0].Body.Close()).To(Succeed())
+
+				response, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(3))
+
+				responses = append(responses, response)
+			})
+		})
+	})
+
+	Describe("LoadSheddingThreshold", func() {
+		var responses []*http.Response
+
+		BeforeEach(func() {
+			pool = transport.NewPool(logger, fakeClock, transport.PoolConfig{
+				LoadSheddingThreshold: 2,
+			})
+
+			responses = nil
+			for _, workerName := range []string{"some-worker", "some-other-worker"} {
+				response, err := pool.RoundTripper(workerName, fakeRoundTripper).RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				responses = append(responses, response)
+			}
+
+			roundTripper = pool.RoundTripper("some-worker", fakeRoundTripper)
+		})
+
+		AfterEach(func() {
+			for _, response := range responses {
+				response.Body.Close()
+			}
+		})
+
+		sendWithPriority := func(priority transport.Priority) error {
+			ctx := transport.WithPriority(context.Background(), priority)
+
+			response, err := roundTripper.RoundTrip(request.WithContext(ctx))
+			if err == nil {
+				responses = append(responses, response)
+			}
+
+			return err
+		}
+
+		It("sheds low priority requests above the threshold", func() {
+			Expect(sendWithPriority(transport.PriorityLow)).To(Equal(transport.ErrLoadShed))
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(2))
+		})
+
+		It("lets normal and high priority requests proceed", func() {
+			Expect(sendWithPriority(transport.PriorityNormal)).To(Succeed())
+			Expect(sendWithPriority(transport.PriorityHigh)).To(Succeed())
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(4))
+		})
+
+		It("treats requests without a priority as normal priority", func() {
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			responses = append(responses, response)
+		})
+
+		It("does not shed low priority requests spawned by a high priority build", func() {
+			buildCtx := transport.WithPriority(context.Background(), transport.PriorityHigh)
+			requestCtx := transport.InheritPriority(transport.WithPriority(context.Background(), transport.PriorityLow), buildCtx)
+
+			response, err := roundTripper.RoundTrip(request.WithContext(requestCtx))
+			Expect(err).NotTo(HaveOccurred())
+
+			responses = append(responses, response)
+		})
+
+		It("accepts low priority requests again once the load drops", func() {
+			Expect(responses[0].Body.Close()).To(Succeed())
+			Expect(sendWithPriority(transport.PriorityLow)).To(Succeed())
+		})
+	})
+
+	Describe("OutlierLatencyFactor", func() {
+		BeforeEach(func() {
+			pool = transport.NewPool(logger, fakeClock, transport.PoolConfig{
+				OutlierLatencyFactor: 3,
+				OutlierCooldown:      time.Minute,
+			})
+
+			fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+				if request.URL.Path == "/slow" {
+					fakeClock.Increment(time.Second)
+				} else {
+					fakeClock.Increment(10 * time.Millisecond)
+				}
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+				}, nil
+			}
+
+			slowURL, err := url.Parse("http://1.2.3.4/slow")
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 3; i++ {
+				for _, workerName := range []string{"worker-a", "worker-b"} {
+					response, err := pool.RoundTripper(workerName, fakeRoundTripper).RoundTrip(request)
+					Expect(err).NotTo(HaveOccurred())
+					response.Body.Close()
+				}
+			}
+
+			response, err := pool.RoundTripper("slow-worker", fakeRoundTripper).RoundTrip(&http.Request{URL: slowURL})
+			Expect(err).NotTo(HaveOccurred())
+			response.Body.Close()
+		})
+
+		It("ejects a worker whose latency is an outlier", func() {
+			Expect(pool.ResolveWorker("slow-worker")).To(Equal(transport.ErrWorkerEjected))
+			Expect(pool.ResolveWorker("worker-a")).To(Succeed())
+			Expect(pool.SelectWorker([]string{"slow-worker", "worker-a"})).To(Equal("worker-a"))
+		})
+
+		It("re-admits it after the cooldown", func() {
+			fakeClock.Increment(time.Minute)
+			Expect(pool.ResolveWorker("slow-worker")).To(Succeed())
+		})
+	})
+
+	Describe("TunnelRoundTripper", func() {
+		var (
+			tunnel         *httptest.Server
+			tunnelURL      *url.URL
+			connectionsL   sync.Mutex
+			connections    int
+			requestedHosts []string
+			clientAddrs    []string
+			deadAddr       string
+			unblock        chan struct{}
+		)
+
+		BeforeEach(func() {
+			connections = 0
+			requestedHosts = nil
+			clientAddrs = nil
+			deadAddr = ""
+			unblock = make(chan struct{})
+
+			tunnel = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				connectionsL.Lock()
+				requestedHosts = append(requestedHosts, r.Host)
+				clientAddrs = append(clientAddrs, r.RemoteAddr)
+				dead := r.RemoteAddr == deadAddr
+				connectionsL.Unlock()
+
+				if dead {
+					<-unblock
+				}
+			}))
+
+			tunnel.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					connectionsL.Lock()
+					connections++
+					connectionsL.Unlock()
+				}
+			}
+
+			tunnel.Start()
+
+			var err error
+			tunnelURL, err = url.Parse(tunnel.URL)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			close(unblock)
+			tunnel.Close()
+		})
+
+		send := func(roundTripper http.RoundTripper, workerURL string) {
+			request, err := http.NewRequest("GET", workerURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ioutil.ReadAll(response.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+		}
+
+		It("sends requests to tunneled workers over one shared connection", func() {
+			send(pool.RoundTripper("worker-1", pool.TunnelRoundTripper(tunnelURL)), "http://worker-1:7777/ping")
+			send(pool.RoundTripper("worker-2", pool.TunnelRoundTripper(tunnelURL)), "http://worker-2:7777/ping")
+
+			connectionsL.Lock()
+			defer connectionsL.Unlock()
+
+			Expect(requestedHosts).To(Equal([]string{"worker-1:7777", "worker-2:7777"}))
+			Expect(connections).To(Equal(1))
+		})
+
+		It("returns the same round tripper for the same tunnel", func() {
+			sameURL, err := url.Parse(tunnel.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pool.TunnelRoundTripper(sameURL)).To(BeIdenticalTo(pool.TunnelRoundTripper(tunnelURL)))
+		})
+
+		Context("when an idle connection to the tunnel has died", func() {
+			BeforeEach(func() {
+				send(pool.RoundTripper("worker-1", pool.TunnelRoundTripper(tunnelURL)), "http://worker-1:7777/ping")
+
+				connectionsL.Lock()
+				deadAddr = clientAddrs[0]
+				connectionsL.Unlock()
+			})
+
+			It("is evicted by probing before the next request reuses it", func() {
+				probed := make(chan struct{})
+				go func() {
+					defer close(probed)
+					pool.ProbeTunnels(time.Second)
+				}()
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(probed).Should(BeClosed())
+
+				send(pool.RoundTripper("worker-1", pool.TunnelRoundTripper(tunnelURL)), "http://worker-1:7777/ping")
+
+				connectionsL.Lock()
+				defer connectionsL.Unlock()
+
+				Expect(connections).To(Equal(2))
+				Expect(clientAddrs[len(clientAddrs)-1]).NotTo(Equal(deadAddr))
+			})
+		})
+
+		Context("when the tunnel's connections are healthy", func() {
+			It("keeps reusing them after probing", func() {
+				send(pool.RoundTripper("worker-1", pool.TunnelRoundTripper(tunnelURL)), "http://worker-1:7777/ping")
+
+				pool.ProbeTunnels(time.Second)
+
+				send(pool.RoundTripper("worker-1", pool.TunnelRoundTripper(tunnelURL)), "http://worker-1:7777/ping")
+
+				connectionsL.Lock()
+				defer connectionsL.Unlock()
+
+				Expect(connections).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("DrainWorker", func() {
+		var (
+			response *http.Response
+			drained  chan struct{}
+		)
+
+		BeforeEach(func() {
+			var err error
+			response, err = roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			drained = make(chan struct{})
+		})
+
+		AfterEach(func() {
+			response.Body.Close()
+			Eventually(drained).Should(BeClosed())
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				defer close(drained)
+				pool.DrainWorker("some-worker", time.Minute)
+			}()
+		})
+
+		It("stops routing new requests to the worker", func() {
+			Eventually(fakeClock.WatcherCount).Should(Equal(1))
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).To(Equal(transport.WorkerDrainingError{WorkerName: "some-worker"}))
+
+			Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(1))
+		})
+
+		It("keeps routing requests to other workers", func() {
+			otherRoundTripper := pool.RoundTripper("some-other-worker", fakeRoundTripper)
+
+			_, err := otherRoundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the in-flight request finishes within the grace period", func() {
+			It("lets it finish without closing it", func() {
+				fakeClock.WaitForWatcherAndIncrement(30 * time.Second)
+				Consistently(drained).ShouldNot(BeClosed())
+
+				actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+				Expect(actualRequest.Context().Err()).To(BeNil())
+
+				Expect(response.Body.Close()).To(Succeed())
+				Eventually(drained).Should(BeClosed())
+			})
+		})
+
+		Context("when the in-flight request is still running after the grace period", func() {
+			It("force-closes it", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Minute)
+				Eventually(drained).Should(BeClosed())
+
+				actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+				Expect(actualRequest.Context().Done()).To(BeClosed())
+			})
+		})
+
+		Context("when there are no requests in flight", func() {
+			BeforeEach(func() {
+				Expect(response.Body.Close()).To(Succeed())
+			})
+
+			It("returns immediately", func() {
+				Eventually(drained).Should(BeClosed())
+			})
+		})
+
+		It("emits the number of requests being drained", func() {
+			drainingConnections := func(connections int) types.GomegaMatcher {
+				return ContainElement(ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Name":       Equal("worker draining connections"),
+					"Value":      Equal(connections),
+					"Attributes": HaveKeyWithValue("worker", "some-worker"),
+				})))
+			}
+
+			Eventually(func() [][]interface{} {
+				return fakeEmitter.Invocations()["Emit"]
+			}).Should(drainingConnections(1))
+
+			Expect(response.Body.Close()).To(Succeed())
+
+			Eventually(func() [][]interface{} {
+				return fakeEmitter.Invocations()["Emit"]
+			}).Should(drainingConnections(0))
+		})
+	})
+})
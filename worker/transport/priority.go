@@ -0,0 +1,45 @@
+package transport
+
+import "context"
+
+// Priority determines the order in which requests waiting for a worker are
+// sent; requests with a higher priority are sent first.
+type Priority int
+
+const (
+	PriorityLow    Priority = 0
+	PriorityNormal Priority = 10
+	PriorityHigh   Priority = 20
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx which makes requests sent with it carry
+// the given priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority carried by ctx, defaulting to
+// PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	priority, found := ctx.Value(priorityKey{}).(Priority)
+	if !found {
+		return PriorityNormal
+	}
+
+	return priority
+}
+
+// InheritPriority returns a copy of ctx which carries the priority of parent,
+// e.g. the context of the build a request is made for, if that is higher
+// than the priority ctx already carries. A request is never given a lower
+// priority than the build that spawned it.
+func InheritPriority(ctx context.Context, parent context.Context) context.Context {
+	inherited := PriorityFromContext(parent)
+	if inherited <= PriorityFromContext(ctx) {
+		return ctx
+	}
+
+	return WithPriority(ctx, inherited)
+}
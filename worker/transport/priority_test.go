@@ -0,0 +1,50 @@
+package transport_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Priority", func() {
+	Describe("PriorityFromContext", func() {
+		It("defaults to normal priority", func() {
+			Expect(transport.PriorityFromContext(context.Background())).To(Equal(transport.PriorityNormal))
+		})
+
+		It("is inherited by contexts derived from the build's context", func() {
+			buildCtx := transport.WithPriority(context.Background(), transport.PriorityHigh)
+
+			stepCtx, cancel := context.WithTimeout(buildCtx, time.Minute)
+			defer cancel()
+
+			Expect(transport.PriorityFromContext(stepCtx)).To(Equal(transport.PriorityHigh))
+		})
+	})
+
+	Describe("InheritPriority", func() {
+		var buildCtx context.Context
+
+		BeforeEach(func() {
+			buildCtx = transport.WithPriority(context.Background(), transport.PriorityHigh)
+		})
+
+		It("raises a child request to the build's priority", func() {
+			requestCtx := transport.WithPriority(context.Background(), transport.PriorityLow)
+
+			inherited := transport.InheritPriority(requestCtx, buildCtx)
+			Expect(transport.PriorityFromContext(inherited)).To(Equal(transport.PriorityHigh))
+		})
+
+		It("keeps the child request's priority if it is higher", func() {
+			lowBuildCtx := transport.WithPriority(context.Background(), transport.PriorityLow)
+
+			inherited := transport.InheritPriority(context.Background(), lowBuildCtx)
+			Expect(transport.PriorityFromContext(inherited)).To(Equal(transport.PriorityNormal))
+		})
+	})
+})
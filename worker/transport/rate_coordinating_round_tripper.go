@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+//go:generate counterfeiter . RateCoordinator
+
+// RateCoordinator counts the requests made to a worker by every ATC, so that
+// all of them together can keep within the worker's request rate limit.
+type RateCoordinator interface {
+	// IncrementRequests counts one more request to the named worker in the
+	// window starting at windowStart, returning the total counted so far.
+	IncrementRequests(workerName string, windowStart time.Time) (int, error)
+}
+
+type rateCoordinatingRoundTripper struct {
+	workerName        string
+	clock             clock.Clock
+	coordinator       RateCoordinator
+	limit             int
+	window            time.Duration
+	innerRoundTripper http.RoundTripper
+}
+
+// NewRateCoordinatingRoundTripper returns a http.RoundTripper which sends no
+// more than limit requests to the named worker per window, counted across
+// every ATC sharing the coordinator. Requests over the limit wait for the
+// next window, or until their context is done.
+func NewRateCoordinatingRoundTripper(workerName string, clock clock.Clock, coordinator RateCoordinator, limit int, window time.Duration, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &rateCoordinatingRoundTripper{
+		workerName:        workerName,
+		clock:             clock,
+		coordinator:       coordinator,
+		limit:             limit,
+		window:            window,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *rateCoordinatingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	for {
+		now := c.clock.Now()
+		windowStart := now.Truncate(c.window)
+
+		count, err := c.coordinator.IncrementRequests(c.workerName, windowStart)
+		if err != nil {
+			return nil, err
+		}
+
+		if count <= c.limit {
+			return c.innerRoundTripper.RoundTrip(request)
+		}
+
+		timer := c.clock.NewTimer(windowStart.Add(c.window).Sub(now))
+
+		select {
+		case <-timer.C():
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateCoordinatingRoundTripper #RoundTrip", func() {
+	const limit = 3
+
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeCoordinator  *transportfakes.FakeRateCoordinator
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+
+		replicaA http.RoundTripper
+		replicaB http.RoundTripper
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(120, 0))
+
+		// stands in for the table shared by every ATC
+		var countsL sync.Mutex
+		counts := map[time.Time]int{}
+
+		fakeCoordinator = new(transportfakes.FakeRateCoordinator)
+		fakeCoordinator.IncrementRequestsStub = func(workerName string, windowStart time.Time) (int, error) {
+			countsL.Lock()
+			defer countsL.Unlock()
+
+			counts[windowStart]++
+			return counts[windowStart], nil
+		}
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+
+		replicaA = transport.NewRateCoordinatingRoundTripper("some-worker", fakeClock, fakeCoordinator, limit, time.Minute, fakeRoundTripper)
+		replicaB = transport.NewRateCoordinatingRoundTripper("some-worker", fakeClock, fakeCoordinator, limit, time.Minute, fakeRoundTripper)
+	})
+
+	It("keeps the combined rate of every replica under the worker's limit", func() {
+		for i := 0; i < 4; i++ {
+			for _, replica := range []http.RoundTripper{replicaA, replicaB} {
+				go func(replica http.RoundTripper) {
+					defer GinkgoRecover()
+
+					_, err := replica.RoundTrip(newBodyRequest(""))
+					Expect(err).NotTo(HaveOccurred())
+				}(replica)
+			}
+		}
+
+		Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(limit))
+		Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(limit))
+
+		fakeClock.WaitForNWatchersAndIncrement(time.Minute, 5)
+
+		Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(2 * limit))
+		Consistently(fakeRoundTripper.RoundTripCallCount).Should(Equal(2 * limit))
+
+		fakeClock.WaitForNWatchersAndIncrement(time.Minute, 2)
+
+		Eventually(fakeRoundTripper.RoundTripCallCount).Should(Equal(8))
+	})
+
+	It("counts requests per window for the worker", func() {
+		_, err := replicaA.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeCoordinator.IncrementRequestsCallCount()).To(Equal(1))
+		workerName, windowStart := fakeCoordinator.IncrementRequestsArgsForCall(0)
+		Expect(workerName).To(Equal("some-worker"))
+		Expect(windowStart).To(Equal(time.Unix(120, 0)))
+	})
+
+	It("gives up waiting for the next window when the request is cancelled", func() {
+		fakeCoordinator.IncrementRequestsStub = nil
+		fakeCoordinator.IncrementRequestsReturns(limit+1, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := replicaA.RoundTrip(newBodyRequest("").WithContext(ctx))
+			errs <- err
+		}()
+
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		cancel()
+
+		Eventually(errs).Should(Receive(Equal(context.Canceled)))
+		Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(0))
+	})
+
+	It("returns the error when the requests cannot be counted", func() {
+		disaster := errors.New("nope")
+		fakeCoordinator.IncrementRequestsStub = nil
+		fakeCoordinator.IncrementRequestsReturns(0, disaster)
+
+		_, err := replicaA.RoundTrip(newBodyRequest(""))
+		Expect(err).To(Equal(disaster))
+		Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(0))
+	})
+})
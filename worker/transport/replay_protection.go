@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	uuid "github.com/nu7hatch/gouuid"
+)
+
+const (
+	// RequestNonceHeader carries a value unique to each attempt of a
+	// non-idempotent request, which the worker refuses to see twice.
+	RequestNonceHeader = "X-Request-Nonce"
+
+	// RequestTimestampHeader carries the time (in Unix seconds) a nonce was
+	// issued, which bounds how long the worker has to remember it.
+	RequestTimestampHeader = "X-Request-Timestamp"
+)
+
+type nonceRoundTripper struct {
+	clock             clock.Clock
+	innerRoundTripper http.RoundTripper
+}
+
+// NewNonceRoundTripper returns a http.RoundTripper which attaches a fresh
+// nonce and timestamp to every non-idempotent request, so that a worker
+// validating them with a NonceCache rejects replays of the request.
+func NewNonceRoundTripper(clock clock.Clock, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &nonceRoundTripper{
+		clock:             clock,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *nonceRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != "POST" && request.Method != "PATCH" {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	nonce, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set(RequestNonceHeader, nonce.String())
+	updatedHeader.Set(RequestTimestampHeader, strconv.FormatInt(c.clock.Now().Unix(), 10))
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+
+	return c.innerRoundTripper.RoundTrip(&updatedRequest)
+}
+
+// NonceCache is used by workers to validate the nonces attached by
+// NewNonceRoundTripper. It remembers the nonces seen within the window;
+// requests issued longer ago than that are rejected outright.
+type NonceCache struct {
+	clock  clock.Clock
+	window time.Duration
+
+	seenL sync.Mutex
+	seen  map[string]time.Time
+}
+
+func NewNonceCache(clock clock.Clock, window time.Duration) *NonceCache {
+	return &NonceCache{
+		clock:  clock,
+		window: window,
+		seen:   map[string]time.Time{},
+	}
+}
+
+// Validate returns ErrRequestReplayed if the request's nonce has been seen
+// before or was issued outside the window. Requests without a nonce are not
+// validated.
+func (cache *NonceCache) Validate(request *http.Request) error {
+	nonce := request.Header.Get(RequestNonceHeader)
+	if nonce == "" {
+		return nil
+	}
+
+	timestamp, err := strconv.ParseInt(request.Header.Get(RequestTimestampHeader), 10, 64)
+	if err != nil {
+		return ErrRequestReplayed
+	}
+
+	issuedAt := time.Unix(timestamp, 0)
+
+	cache.seenL.Lock()
+	defer cache.seenL.Unlock()
+
+	now := cache.clock.Now()
+	for seenNonce, seenAt := range cache.seen {
+		if now.Sub(seenAt) > cache.window {
+			delete(cache.seen, seenNonce)
+		}
+	}
+
+	if now.Sub(issuedAt) > cache.window || issuedAt.Sub(now) > cache.window {
+		return ErrRequestReplayed
+	}
+
+	if _, found := cache.seen[nonce]; found {
+		return ErrRequestReplayed
+	}
+
+	cache.seen[nonce] = issuedAt
+
+	return nil
+}
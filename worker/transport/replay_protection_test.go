@@ -0,0 +1,103 @@
+package transport_test
+
+import (
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replay protection", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		nonces           *transport.NonceCache
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		sentRequests []*http.Request
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		nonces = transport.NewNonceCache(fakeClock, time.Minute)
+
+		sentRequests = nil
+
+		// a fake worker which rejects requests whose nonce does not validate
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			sentRequests = append(sentRequests, request)
+
+			if nonces.Validate(request) != nil {
+				return &http.Response{StatusCode: http.StatusConflict}, nil
+			}
+
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}
+
+		roundTripper = transport.NewNonceRoundTripper(fakeClock, fakeRoundTripper)
+	})
+
+	Context("with a non-idempotent request", func() {
+		var request *http.Request
+
+		BeforeEach(func() {
+			request = newBodyRequest("")
+			request.Method = "POST"
+		})
+
+		It("attaches a fresh nonce to every request", func() {
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			first := sentRequests[0].Header.Get(transport.RequestNonceHeader)
+			Expect(first).NotTo(BeEmpty())
+			Expect(sentRequests[1].Header.Get(transport.RequestNonceHeader)).NotTo(Equal(first))
+		})
+
+		It("does not modify the original request", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(request.Header.Get(transport.RequestNonceHeader)).To(BeEmpty())
+		})
+
+		It("has a replayed request rejected by the worker", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			response, err := fakeRoundTripper.RoundTrip(sentRequests[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusConflict))
+		})
+
+		It("has a request replayed after the window rejected by the worker", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClock.Increment(2 * time.Minute)
+
+			Expect(nonces.Validate(sentRequests[0])).To(Equal(transport.ErrRequestReplayed))
+		})
+	})
+
+	Context("with an idempotent request", func() {
+		It("sends it without a nonce", func() {
+			_, err := roundTripper.RoundTrip(newBodyRequest(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sentRequests[0].Header.Get(transport.RequestNonceHeader)).To(BeEmpty())
+			Expect(nonces.Validate(sentRequests[0])).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package transport
+
+// RequestOption adjusts how the worker for a single request is selected.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	excluded map[string]bool
+}
+
+func newRequestOptions(options []RequestOption) requestOptions {
+	opts := requestOptions{
+		excluded: map[string]bool{},
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return opts
+}
+
+// Exclude removes the named worker from the candidates for the request, e.g.
+// the worker a previous attempt of the request just failed on.
+func Exclude(workerName string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.excluded[workerName] = true
+	}
+}
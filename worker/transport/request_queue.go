@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+type QueuedRequest struct {
+	Priority   Priority
+	EnqueuedAt time.Time
+
+	seq int
+}
+
+// RequestQueue orders the requests waiting for a worker by priority. A waiting
+// request gains one priority level for every aging interval it has spent in
+// the queue, so that low priority requests are not starved by a steady stream
+// of higher priority ones.
+//
+// A RequestQueue is not safe for concurrent use.
+type RequestQueue struct {
+	clock         clock.Clock
+	agingInterval time.Duration
+
+	nextSeq int
+	waiting []*QueuedRequest
+}
+
+func NewRequestQueue(clock clock.Clock, agingInterval time.Duration) *RequestQueue {
+	return &RequestQueue{
+		clock:         clock,
+		agingInterval: agingInterval,
+	}
+}
+
+func (queue *RequestQueue) Len() int {
+	return len(queue.waiting)
+}
+
+func (queue *RequestQueue) Push(priority Priority) *QueuedRequest {
+	request := &QueuedRequest{
+		Priority:   priority,
+		EnqueuedAt: queue.clock.Now(),
+		seq:        queue.nextSeq,
+	}
+
+	queue.nextSeq++
+	queue.waiting = append(queue.waiting, request)
+
+	return request
+}
+
+// Pop removes and returns the request with the highest aged priority. Requests
+// with the same aged priority are returned in the order they were pushed.
+func (queue *RequestQueue) Pop() (*QueuedRequest, bool) {
+	if len(queue.waiting) == 0 {
+		return nil, false
+	}
+
+	now := queue.clock.Now()
+
+	next := 0
+	for i, request := range queue.waiting {
+		if queue.agedPriority(request, now) > queue.agedPriority(queue.waiting[next], now) {
+			next = i
+		}
+	}
+
+	request := queue.waiting[next]
+	queue.waiting = append(queue.waiting[:next], queue.waiting[next+1:]...)
+
+	return request, true
+}
+
+// Remove takes the request out of the queue without it being sent, e.g. when
+// its caller gave up waiting.
+func (queue *RequestQueue) Remove(request *QueuedRequest) {
+	for i, waiting := range queue.waiting {
+		if waiting == request {
+			queue.waiting = append(queue.waiting[:i], queue.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func (queue *RequestQueue) agedPriority(request *QueuedRequest, now time.Time) Priority {
+	if queue.agingInterval <= 0 {
+		return request.Priority
+	}
+
+	return request.Priority + Priority(now.Sub(request.EnqueuedAt)/queue.agingInterval)
+}
@@ -0,0 +1,91 @@
+package transport_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RequestQueue", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		queue     *transport.RequestQueue
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		queue = transport.NewRequestQueue(fakeClock, time.Second)
+	})
+
+	It("pops the highest priority request first", func() {
+		low := queue.Push(transport.PriorityLow)
+		high := queue.Push(transport.PriorityHigh)
+		normal := queue.Push(transport.PriorityNormal)
+
+		Expect(pop(queue)).To(Equal(high))
+		Expect(pop(queue)).To(Equal(normal))
+		Expect(pop(queue)).To(Equal(low))
+
+		_, found := queue.Pop()
+		Expect(found).To(BeFalse())
+	})
+
+	It("pops requests of the same priority in the order they were pushed", func() {
+		first := queue.Push(transport.PriorityNormal)
+		second := queue.Push(transport.PriorityNormal)
+
+		Expect(pop(queue)).To(Equal(first))
+		Expect(pop(queue)).To(Equal(second))
+	})
+
+	Describe("aging", func() {
+		var low *transport.QueuedRequest
+
+		BeforeEach(func() {
+			low = queue.Push(transport.PriorityLow)
+		})
+
+		It("keeps newer high priority requests ahead while the low priority one has not waited long", func() {
+			fakeClock.Increment(5 * time.Second)
+
+			high := queue.Push(transport.PriorityHigh)
+			Expect(pop(queue)).To(Equal(high))
+			Expect(pop(queue)).To(Equal(low))
+		})
+
+		It("eventually lets a long-waiting low priority request preempt newer high priority ones", func() {
+			popped := []*transport.QueuedRequest{}
+			for len(popped) == 0 || popped[len(popped)-1] != low {
+				Expect(len(popped)).To(BeNumerically("<", 30), "low priority request was starved")
+
+				fakeClock.Increment(time.Second)
+				queue.Push(transport.PriorityHigh)
+				popped = append(popped, pop(queue))
+			}
+
+			Expect(len(popped)).To(BeNumerically(">", 1))
+			Expect(queue.Len()).To(Equal(1))
+		})
+	})
+
+	Describe("Remove", func() {
+		It("takes the request out of the queue", func() {
+			first := queue.Push(transport.PriorityHigh)
+			second := queue.Push(transport.PriorityLow)
+
+			queue.Remove(first)
+			Expect(queue.Len()).To(Equal(1))
+			Expect(pop(queue)).To(Equal(second))
+		})
+	})
+})
+
+func pop(queue *transport.RequestQueue) *transport.QueuedRequest {
+	request, found := queue.Pop()
+	Expect(found).To(BeTrue())
+	return request
+}
@@ -0,0 +1,33 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/concourse/atc/metric"
+	"github.com/concourse/retryhttp"
+)
+
+type retryExhaustionRoundTripper struct {
+	retryer           retryhttp.Retryer
+	innerRoundTripper http.RoundTripper
+}
+
+// NewRetryExhaustionRoundTripper returns a http.RoundTripper which counts the
+// requests that exhausted their retries. It is meant to wrap a retrying
+// http.RoundTripper using the same retryer: a retryable error coming out of
+// it means it gave up on retrying the request.
+func NewRetryExhaustionRoundTripper(retryer retryhttp.Retryer, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &retryExhaustionRoundTripper{
+		retryer:           retryer,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *retryExhaustionRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil && c.retryer.IsRetryable(err) {
+		metric.WorkerRequestRetriesExhausted.Inc()
+	}
+
+	return response, err
+}
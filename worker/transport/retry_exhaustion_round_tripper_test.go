@@ -0,0 +1,159 @@
+package transport_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/cenkalti/backoff"
+	"github.com/concourse/atc/metric"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+	"github.com/concourse/retryhttp"
+	"github.com/concourse/retryhttp/retryhttpfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryExhaustionRoundTripper #RoundTrip", func() {
+	var (
+		fakeRetryer      *retryhttpfakes.FakeRetryer
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+		request          *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeRetryer = new(retryhttpfakes.FakeRetryer)
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+
+		roundTripper = transport.NewRetryExhaustionRoundTripper(fakeRetryer, fakeRoundTripper)
+
+		requestURL, err := url.Parse("http://1.2.3.4/something")
+		Expect(err).NotTo(HaveOccurred())
+
+		request = &http.Request{
+			URL: requestURL,
+		}
+
+		metric.WorkerRequestRetriesExhausted.Delta()
+	})
+
+	Context("when the retries are given up with a retryable error", func() {
+		var retryableErr = errors.New("still unreachable")
+
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripReturns(nil, retryableErr)
+			fakeRetryer.IsRetryableReturns(true)
+		})
+
+		It("counts the request exactly once", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).To(Equal(retryableErr))
+
+			Expect(fakeRetryer.IsRetryableArgsForCall(0)).To(Equal(retryableErr))
+			Expect(metric.WorkerRequestRetriesExhausted.Delta()).To(Equal(1))
+		})
+	})
+
+	Context("when the request fails with an error that is not retried", func() {
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripReturns(nil, errors.New("fatal"))
+			fakeRetryer.IsRetryableReturns(false)
+		})
+
+		It("does not count it", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).To(HaveOccurred())
+
+			Expect(metric.WorkerRequestRetriesExhausted.Delta()).To(BeZero())
+		})
+	})
+
+	Context("when the request succeeds", func() {
+		BeforeEach(func() {
+			fakeRoundTripper.RoundTripReturns(&http.Response{StatusCode: http.StatusTeapot}, nil)
+		})
+
+		It("does not count it", func() {
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusTeapot))
+
+			Expect(fakeRetryer.IsRetryableCallCount()).To(BeZero())
+			Expect(metric.WorkerRequestRetriesExhausted.Delta()).To(BeZero())
+		})
+	})
+
+	Context("when wrapping a retryhttp.RetryRoundTripper", func() {
+		const attempts = 3
+
+		var unreachableErr = transport.WorkerUnreachableError{
+			WorkerName:  "some-worker",
+			WorkerState: "stalled",
+		}
+
+		BeforeEach(func() {
+			retryer := &transport.UnreachableWorkerRetryer{
+				DelegateRetryer: &retryhttp.DefaultRetryer{},
+			}
+
+			fakeBackOff := new(retryhttpfakes.FakeBackOff)
+			fakeBackOff.NextBackOffStub = func() time.Duration {
+				if fakeBackOff.NextBackOffCallCount() < attempts {
+					return 0
+				}
+
+				return backoff.Stop
+			}
+
+			fakeBackOffFactory := new(retryhttpfakes.FakeBackOffFactory)
+			fakeBackOffFactory.NewBackOffReturns(fakeBackOff)
+
+			// wired up like the garden connection factory does
+			roundTripper = transport.NewRetryExhaustionRoundTripper(retryer, &retryhttp.RetryRoundTripper{
+				Logger:         lagertest.NewTestLogger("test"),
+				BackOffFactory: fakeBackOffFactory,
+				RoundTripper:   fakeRoundTripper,
+				Retryer:        retryer,
+			})
+		})
+
+		Context("when every attempt fails", func() {
+			BeforeEach(func() {
+				fakeRoundTripper.RoundTripReturns(nil, unreachableErr)
+			})
+
+			It("counts the request exactly once", func() {
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).To(Equal(unreachableErr))
+
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(attempts))
+				Expect(metric.WorkerRequestRetriesExhausted.Delta()).To(Equal(1))
+			})
+		})
+
+		Context("when the last attempt succeeds", func() {
+			BeforeEach(func() {
+				fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+					if fakeRoundTripper.RoundTripCallCount() < attempts {
+						return nil, unreachableErr
+					}
+
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				}
+			})
+
+			It("does not count it", func() {
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeRoundTripper.RoundTripCallCount()).To(Equal(attempts))
+				Expect(metric.WorkerRequestRetriesExhausted.Delta()).To(BeZero())
+			})
+		})
+	})
+})
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// maxBufferedBodySize is the largest request body that is buffered in order
+// to send the request more than once, e.g. to mirror it. Requests with larger
+// bodies, or bodies of unknown length (e.g. volume streams), are only ever
+// sent once.
+const maxBufferedBodySize = 1024 * 1024
+
+type shadowingRoundTripper struct {
+	logger            lager.Logger
+	fraction          float64
+	shadows           []http.RoundTripper
+	innerRoundTripper http.RoundTripper
+
+	randL sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewShadowingRoundTripper returns a http.RoundTripper which mirrors the given
+// fraction (between 0 and 1) of requests to each of the shadows, e.g. new
+// workers being tried out with live traffic. Only requests without side
+// effects are mirrored, i.e. GET, HEAD and OPTIONS requests. Only the response
+// of the inner round tripper is returned; the shadows' responses are
+// discarded.
+func NewShadowingRoundTripper(logger lager.Logger, fraction float64, shadows []http.RoundTripper, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &shadowingRoundTripper{
+		logger:            logger,
+		fraction:          fraction,
+		shadows:           shadows,
+		innerRoundTripper: innerRoundTripper,
+		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *shadowingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !c.shouldShadow(request) {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, shadow := range c.shadows {
+		go c.shadow(shadow, copyRequest(request, body))
+	}
+
+	return c.innerRoundTripper.RoundTrip(copyRequest(request, body))
+}
+
+func (c *shadowingRoundTripper) shouldShadow(request *http.Request) bool {
+	if request.Body != nil && (request.ContentLength <= 0 || request.ContentLength > maxBufferedBodySize) {
+		return false
+	}
+
+	// shadows must not take on side effects, e.g. create containers nothing
+	// tracks or garbage-collects. An idempotency key does not make a request
+	// safe to mirror: it only guards against duplicates on the same worker.
+	switch request.Method {
+	case "", "GET", "HEAD", "OPTIONS":
+	default:
+		return false
+	}
+
+	c.randL.Lock()
+	defer c.randL.Unlock()
+
+	return c.rand.Float64() < c.fraction
+}
+
+func (c *shadowingRoundTripper) shadow(shadow http.RoundTripper, request *http.Request) {
+	response, err := shadow.RoundTrip(request)
+	if err != nil {
+		c.logger.Debug("failed-to-shadow-request", lager.Data{"error": err.Error()})
+		return
+	}
+
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+}
+
+func copyRequest(request *http.Request, body []byte) *http.Request {
+	header := http.Header{}
+	for k, v := range request.Header {
+		header[k] = v
+	}
+
+	url := *request.URL
+
+	copied := *request
+	copied.URL = &url
+	copied.Header = header
+
+	if request.Body != nil {
+		copied.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return &copied
+}
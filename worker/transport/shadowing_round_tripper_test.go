@@ -0,0 +1,182 @@
+package transport_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShadowingRoundTripper #RoundTrip", func() {
+	var (
+		fakePrimary  *transportfakes.FakeRoundTripper
+		fakeShadow   *transportfakes.FakeRoundTripper
+		roundTripper http.RoundTripper
+		fraction     float64
+
+		shadowedBodies chan string
+	)
+
+	newShadowableRequest := func(body string) *http.Request {
+		request := newBodyRequest(body)
+		request.Method = "GET"
+		return request
+	}
+
+	BeforeEach(func() {
+		fraction = 1
+
+		fakePrimary = new(transportfakes.FakeRoundTripper)
+		fakePrimary.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			body, err := ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("primary:" + string(body))),
+			}, nil
+		}
+
+		shadowedBodies = make(chan string, 10)
+
+		fakeShadow = new(transportfakes.FakeRoundTripper)
+		fakeShadow.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			body, err := ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			shadowedBodies <- string(body)
+
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       ioutil.NopCloser(strings.NewReader("shadow")),
+			}, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		roundTripper = transport.NewShadowingRoundTripper(
+			lagertest.NewTestLogger("test"),
+			fraction,
+			[]http.RoundTripper{fakeShadow},
+			fakePrimary,
+		)
+	})
+
+	It("mirrors the request to the shadow and returns the primary response", func() {
+		response, err := roundTripper.RoundTrip(newShadowableRequest("some-body"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("primary:some-body"))
+
+		Eventually(shadowedBodies).Should(Receive(Equal("some-body")))
+		Expect(fakePrimary.RoundTripCallCount()).To(Equal(1))
+	})
+
+	Context("when the shadow fails", func() {
+		BeforeEach(func() {
+			fakeShadow.RoundTripStub = func(*http.Request) (*http.Response, error) {
+				shadowedBodies <- ""
+				return nil, errors.New("shadow disaster")
+			}
+		})
+
+		It("does not affect the primary response", func() {
+			response, err := roundTripper.RoundTrip(newShadowableRequest("some-body"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			Eventually(shadowedBodies).Should(Receive())
+		})
+	})
+
+	Context("when the request may have side effects", func() {
+		It("does not shadow it", func() {
+			request := newBodyRequest("some-container-spec")
+			request.Method = "POST"
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Consistently(shadowedBodies).ShouldNot(Receive())
+			Expect(fakePrimary.RoundTripCallCount()).To(Equal(1))
+		})
+
+		It("does not shadow it even when it carries an idempotency key", func() {
+			request := newBodyRequest("some-container-spec")
+			request.Method = "POST"
+			request.URL.Path = "/containers"
+			request.Header.Set(transport.IdempotencyTokenHeader, "some-token")
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Consistently(shadowedBodies).ShouldNot(Receive())
+			Expect(fakeShadow.RoundTripCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the request is a GET", func() {
+		BeforeEach(func() {
+			response := &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}
+
+			fakePrimary.RoundTripStub = nil
+			fakePrimary.RoundTripReturns(response, nil)
+
+			fakeShadow.RoundTripStub = nil
+			fakeShadow.RoundTripReturns(response, nil)
+		})
+
+		It("shadows it", func() {
+			request := newBodyRequest("")
+			request.Method = "GET"
+			request.Body = nil
+			request.ContentLength = 0
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(fakeShadow.RoundTripCallCount).Should(Equal(1))
+		})
+	})
+
+	Context("when the request's body is of unknown length", func() {
+		It("does not shadow it", func() {
+			request := newShadowableRequest("some-stream")
+			request.ContentLength = -1
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Consistently(shadowedBodies).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the fraction is 0", func() {
+		BeforeEach(func() {
+			fraction = 0
+		})
+
+		It("does not shadow any request", func() {
+			for i := 0; i < 10; i++ {
+				_, err := roundTripper.RoundTrip(newShadowableRequest("some-body"))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Consistently(shadowedBodies).ShouldNot(Receive())
+			Expect(fakePrimary.RoundTripCallCount()).To(Equal(10))
+		})
+	})
+})
@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/concourse/atc/metric"
+)
+
+// SlowStreamPolicy decides when a response stream from a worker counts as
+// slow, and what to do about it.
+type SlowStreamPolicy struct {
+	// BytesPerSecond is the throughput floor under which a stream is slow.
+	BytesPerSecond int64
+
+	// Interval is how long throughput is measured over before being compared
+	// to the floor.
+	Interval time.Duration
+
+	// Abort makes a slow stream fail with ErrStreamTooSlow rather than only
+	// being reported.
+	Abort bool
+}
+
+type slowStreamRoundTripper struct {
+	logger            lager.Logger
+	workerName        string
+	clock             clock.Clock
+	policy            SlowStreamPolicy
+	innerRoundTripper http.RoundTripper
+}
+
+// NewSlowStreamRoundTripper returns a http.RoundTripper which measures how
+// fast each response body from the named worker is read, e.g. a volume
+// stream, and reports streams falling under the policy's floor. Per the
+// policy a slow stream is also aborted. Measuring starts with the first read,
+// so that a caller yet to read the body is not blamed on the worker.
+//
+// A policy without a floor or interval disables detection.
+func NewSlowStreamRoundTripper(logger lager.Logger, workerName string, clock clock.Clock, policy SlowStreamPolicy, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	if policy.BytesPerSecond <= 0 || policy.Interval <= 0 {
+		return innerRoundTripper
+	}
+
+	return &slowStreamRoundTripper{
+		logger:            logger,
+		workerName:        workerName,
+		clock:             clock,
+		policy:            policy,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *slowStreamRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &slowStreamBody{
+		ReadCloser: response.Body,
+		done:       make(chan struct{}),
+	}
+
+	body.monitor = func() {
+		c.monitor(request, body)
+	}
+
+	response.Body = body
+
+	return response, nil
+}
+
+func (c *slowStreamRoundTripper) monitor(request *http.Request, body *slowStreamBody) {
+	ticker := c.clock.NewTicker(c.policy.Interval)
+	defer ticker.Stop()
+
+	floor := int64(float64(c.policy.BytesPerSecond) * c.policy.Interval.Seconds())
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-body.done:
+			return
+		}
+
+		read := atomic.SwapInt64(&body.read, 0)
+		if read >= floor {
+			continue
+		}
+
+		if !body.slow {
+			body.slow = true
+
+			metric.WorkerSlowStreams.Inc()
+
+			c.logger.Info("slow-stream", lager.Data{
+				"worker":           c.workerName,
+				"path":             request.URL.Path,
+				"bytes-per-second": float64(read) / c.policy.Interval.Seconds(),
+				"abort":            c.policy.Abort,
+			})
+		}
+
+		if c.policy.Abort {
+			atomic.StoreInt32(&body.aborted, 1)
+			body.ReadCloser.Close()
+			return
+		}
+	}
+}
+
+type slowStreamBody struct {
+	io.ReadCloser
+
+	read    int64
+	aborted int32
+
+	// only touched by the monitoring goroutine
+	slow bool
+
+	monitor     func()
+	monitorOnce sync.Once
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (body *slowStreamBody) Read(p []byte) (int, error) {
+	body.monitorOnce.Do(func() {
+		go body.monitor()
+	})
+
+	n, err := body.ReadCloser.Read(p)
+	atomic.AddInt64(&body.read, int64(n))
+
+	if err != nil {
+		if atomic.LoadInt32(&body.aborted) == 1 {
+			err = ErrStreamTooSlow
+		}
+
+		body.finish()
+	}
+
+	return n, err
+}
+
+func (body *slowStreamBody) Close() error {
+	body.finish()
+	return body.ReadCloser.Close()
+}
+
+func (body *slowStreamBody) finish() {
+	body.doneOnce.Do(func() {
+		close(body.done)
+	})
+}
@@ -0,0 +1,176 @@
+package transport_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/concourse/atc/metric"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SlowStreamRoundTripper #RoundTrip", func() {
+	const bytesPerSecond = 1024
+
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		policy           transport.SlowStreamPolicy
+
+		streamReader *io.PipeReader
+		streamWriter *io.PipeWriter
+
+		response *http.Response
+		streamed chan []byte
+		errs     chan error
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		streamReader, streamWriter = io.Pipe()
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       streamReader,
+			}, nil
+		}
+
+		policy = transport.SlowStreamPolicy{
+			BytesPerSecond: bytesPerSecond,
+			Interval:       time.Second,
+		}
+
+		metric.WorkerSlowStreams.Delta()
+	})
+
+	JustBeforeEach(func() {
+		roundTripper := transport.NewSlowStreamRoundTripper(logger, "some-worker", fakeClock, policy, fakeRoundTripper)
+
+		var err error
+		response, err = roundTripper.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+
+		streamed = make(chan []byte, 1)
+		errs = make(chan error, 1)
+		go func(body io.Reader, streamed chan<- []byte, errs chan<- error) {
+			content, err := ioutil.ReadAll(body)
+			streamed <- content
+			errs <- err
+		}(response.Body, streamed, errs)
+	})
+
+	AfterEach(func() {
+		streamWriter.Close()
+	})
+
+	Context("when the stream keeps above the floor", func() {
+		It("does not report it", func() {
+			_, err := streamWriter.Write([]byte(strings.Repeat("x", bytesPerSecond)))
+			Expect(err).NotTo(HaveOccurred())
+
+			// the stream has been read up to here once the next write goes through
+			_, err = streamWriter.Write([]byte("x"))
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Consistently(logger.LogMessages).Should(BeEmpty())
+
+			Expect(streamWriter.Close()).To(Succeed())
+			Eventually(errs).Should(Receive(BeNil()))
+			Expect(metric.WorkerSlowStreams.Delta()).To(Equal(0))
+		})
+	})
+
+	Context("when the stream stalls", func() {
+		Context("when the policy only reports", func() {
+			It("reports the stream and lets it finish", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				Eventually(logger.LogMessages).Should(ConsistOf("test.slow-stream"))
+				Expect(metric.WorkerSlowStreams.Delta()).To(Equal(1))
+
+				_, err := streamWriter.Write([]byte("late-content"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(streamWriter.Close()).To(Succeed())
+
+				Eventually(streamed).Should(Receive(Equal([]byte("late-content"))))
+				Expect(<-errs).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when the policy aborts", func() {
+			BeforeEach(func() {
+				policy.Abort = true
+			})
+
+			It("reports the stream and aborts it", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				Eventually(errs).Should(Receive(Equal(transport.ErrStreamTooSlow)))
+				Expect(logger.LogMessages()).To(ConsistOf("test.slow-stream"))
+				Expect(metric.WorkerSlowStreams.Delta()).To(Equal(1))
+			})
+		})
+	})
+})
+
+var _ = Describe("SlowStreamRoundTripper", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeClock        *fakeclock.FakeClock
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+			}, nil
+		}
+	})
+
+	It("does not measure a stream before it is first read", func() {
+		roundTripper := transport.NewSlowStreamRoundTripper(logger, "some-worker", fakeClock, transport.SlowStreamPolicy{
+			BytesPerSecond: 1024,
+			Interval:       time.Second,
+			Abort:          true,
+		}, fakeRoundTripper)
+
+		response, err := roundTripper.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+
+		Consistently(fakeClock.WatcherCount).Should(BeZero())
+		fakeClock.Increment(time.Minute)
+
+		Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("some-body")))
+		Expect(logger.LogMessages()).To(BeEmpty())
+	})
+
+	It("is disabled by a policy without a floor or interval", func() {
+		roundTripper := transport.NewSlowStreamRoundTripper(logger, "some-worker", fakeClock, transport.SlowStreamPolicy{}, fakeRoundTripper)
+		Expect(roundTripper).To(BeIdenticalTo(fakeRoundTripper))
+
+		response, err := roundTripper.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("some-body")))
+	})
+})
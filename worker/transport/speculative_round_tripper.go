@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+)
+
+type speculativeRoundTripper struct {
+	primaryRoundTripper   http.RoundTripper
+	secondaryRoundTripper http.RoundTripper
+}
+
+// NewSpeculativeRoundTripper returns a http.RoundTripper which sends every
+// GET, HEAD and OPTIONS request to both the primary and the secondary worker
+// at once, e.g. for latency-critical builds. The first successful response is
+// returned and the slower request is cancelled. A server error (5xx) does not
+// count as successful: it is only returned if the other request fails too.
+//
+// Other requests, and requests whose body is too large to buffer, are only
+// sent to the primary worker.
+func NewSpeculativeRoundTripper(primaryRoundTripper http.RoundTripper, secondaryRoundTripper http.RoundTripper) http.RoundTripper {
+	return &speculativeRoundTripper{
+		primaryRoundTripper:   primaryRoundTripper,
+		secondaryRoundTripper: secondaryRoundTripper,
+	}
+}
+
+type speculativeResult struct {
+	attempt  int
+	response *http.Response
+	err      error
+}
+
+func (c *speculativeRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !canSpeculate(request) {
+		return c.primaryRoundTripper.RoundTrip(request)
+	}
+
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	roundTrippers := []http.RoundTripper{c.primaryRoundTripper, c.secondaryRoundTripper}
+
+	cancels := make([]context.CancelFunc, len(roundTrippers))
+	results := make(chan speculativeResult, len(roundTrippers))
+
+	for attempt, roundTripper := range roundTrippers {
+		ctx, cancel := context.WithCancel(request.Context())
+		cancels[attempt] = cancel
+
+		go func(attempt int, roundTripper http.RoundTripper, request *http.Request) {
+			response, err := roundTripper.RoundTrip(request)
+			results <- speculativeResult{attempt: attempt, response: response, err: err}
+		}(attempt, roundTripper, copyRequest(request, body).WithContext(ctx))
+	}
+
+	// a server error is held back in case the other worker does better, and
+	// only returned if it does not
+	var failed *speculativeResult
+
+	var err error
+	for pending := len(roundTrippers); pending > 0; pending-- {
+		result := <-results
+		if result.err != nil {
+			cancels[result.attempt]()
+			err = result.err
+			continue
+		}
+
+		if result.response.StatusCode >= 500 {
+			if failed == nil {
+				failed = &result
+			} else {
+				result.response.Body.Close()
+				cancels[result.attempt]()
+			}
+
+			continue
+		}
+
+		for attempt, cancel := range cancels {
+			if attempt != result.attempt {
+				cancel()
+			}
+		}
+
+		if failed != nil {
+			failed.response.Body.Close()
+		}
+
+		go discardResponses(results, pending-1)
+
+		return withCancellingBody(result.response, cancels[result.attempt]), nil
+	}
+
+	if failed != nil {
+		return withCancellingBody(failed.response, cancels[failed.attempt]), nil
+	}
+
+	return nil, err
+}
+
+func withCancellingBody(response *http.Response, cancel context.CancelFunc) *http.Response {
+	response.Body = &cancellingBody{
+		ReadCloser: response.Body,
+		cancel:     cancel,
+	}
+
+	return response
+}
+
+// discardResponses closes any response still returned by the requests that
+// lost the race.
+func discardResponses(results <-chan speculativeResult, pending int) {
+	for ; pending > 0; pending-- {
+		result := <-results
+		if result.err == nil {
+			result.response.Body.Close()
+		}
+	}
+}
+
+func canSpeculate(request *http.Request) bool {
+	if request.Body != nil && (request.ContentLength <= 0 || request.ContentLength > maxBufferedBodySize) {
+		return false
+	}
+
+	// cancelling the slower request does not undo what it already did on its
+	// worker, so only requests without side effects are sent to both. An
+	// idempotency key does not help: it only guards against duplicates on the
+	// same worker.
+	switch request.Method {
+	case "", "GET", "HEAD", "OPTIONS":
+		return true
+	default:
+		return false
+	}
+}
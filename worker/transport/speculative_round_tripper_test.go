@@ -0,0 +1,178 @@
+package transport_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpeculativeRoundTripper #RoundTrip", func() {
+	var (
+		fastRoundTripper *transportfakes.FakeRoundTripper
+		slowRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+
+		slowRequests chan *http.Request
+	)
+
+	respond := func(body string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}
+
+	BeforeEach(func() {
+		fastRoundTripper = new(transportfakes.FakeRoundTripper)
+		fastRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return respond("fast-body")
+		}
+
+		slowRequests = make(chan *http.Request, 1)
+
+		slowRoundTripper = new(transportfakes.FakeRoundTripper)
+		slowRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			slowRequests <- request
+			<-request.Context().Done()
+			return nil, request.Context().Err()
+		}
+
+		roundTripper = transport.NewSpeculativeRoundTripper(slowRoundTripper, fastRoundTripper)
+	})
+
+	Context("when the request has no side effects", func() {
+		var request *http.Request
+
+		BeforeEach(func() {
+			request = newBodyRequest("some-body")
+			request.Method = "GET"
+		})
+
+		It("returns the faster response and cancels the slower request", func() {
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("fast-body")))
+			Expect(response.Body.Close()).To(Succeed())
+
+			var slowRequest *http.Request
+			Eventually(slowRequests).Should(Receive(&slowRequest))
+			Eventually(slowRequest.Context().Done()).Should(BeClosed())
+		})
+
+		It("sends the same request to both workers", func() {
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fastRoundTripper.RoundTripCallCount()).To(Equal(1))
+			Expect(ioutil.ReadAll(fastRoundTripper.RoundTripArgsForCall(0).Body)).To(Equal([]byte("some-body")))
+
+			var slowRequest *http.Request
+			Eventually(slowRequests).Should(Receive(&slowRequest))
+			Expect(ioutil.ReadAll(slowRequest.Body)).To(Equal([]byte("some-body")))
+		})
+
+		Context("when the faster request fails", func() {
+			BeforeEach(func() {
+				fastRoundTripper.RoundTripReturns(nil, errors.New("nope"))
+
+				slowRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+					return respond("slow-body")
+				}
+			})
+
+			It("waits for the other response", func() {
+				response, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("slow-body")))
+			})
+		})
+
+		Context("when the faster worker responds with a server error", func() {
+			BeforeEach(func() {
+				fastRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusInternalServerError,
+						Body:       ioutil.NopCloser(strings.NewReader("fast-error")),
+					}, nil
+				}
+
+				slowRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+					return respond("slow-body")
+				}
+			})
+
+			It("waits for the other response", func() {
+				response, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("slow-body")))
+			})
+
+			Context("when the other request fails too", func() {
+				BeforeEach(func() {
+					slowRoundTripper.RoundTripStub = nil
+					slowRoundTripper.RoundTripReturns(nil, errors.New("nope"))
+				})
+
+				It("returns the server error", func() {
+					response, err := roundTripper.RoundTrip(request)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(response.StatusCode).To(Equal(http.StatusInternalServerError))
+					Expect(ioutil.ReadAll(response.Body)).To(Equal([]byte("fast-error")))
+				})
+			})
+		})
+
+		Context("when both requests fail", func() {
+			BeforeEach(func() {
+				fastRoundTripper.RoundTripReturns(nil, errors.New("nope"))
+				slowRoundTripper.RoundTripReturns(nil, errors.New("nope"))
+			})
+
+			It("returns the error", func() {
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).To(MatchError("nope"))
+			})
+		})
+	})
+
+	Context("when the request may have side effects", func() {
+		BeforeEach(func() {
+			slowRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+				return respond("slow-body")
+			}
+		})
+
+		It("only sends it to the primary worker", func() {
+			for _, method := range []string{"PUT", "DELETE", "POST"} {
+				request := newBodyRequest("some-body")
+				request.Method = method
+
+				_, err := roundTripper.RoundTrip(request)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(slowRoundTripper.RoundTripCallCount()).To(Equal(3))
+			Expect(fastRoundTripper.RoundTripCallCount()).To(Equal(0))
+		})
+
+		It("only sends it to the primary worker even when it carries an idempotency key", func() {
+			request := newBodyRequest("some-container-spec")
+			request.Method = "POST"
+			request.Header.Set(transport.IdempotencyTokenHeader, "some-token")
+
+			_, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(slowRoundTripper.RoundTripCallCount()).To(Equal(1))
+			Expect(fastRoundTripper.RoundTripCallCount()).To(Equal(0))
+		})
+	})
+})
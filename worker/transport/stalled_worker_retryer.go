@@ -6,7 +6,17 @@ type UnreachableWorkerRetryer struct {
 	DelegateRetryer retryhttp.Retryer
 }
 
+// IsRetryable consults ClassifyFailure: a capacity failure is never retried,
+// as sending the request to the same worker again will not help, and a
+// WorkerUnreachableError is always retried, as the request never reached the
+// worker. Everything else is left to DelegateRetryer, which knows which errors
+// are safe to retry. This includes ErrRequestTimedOut, since the worker may
+// already have acted on a request that timed out.
 func (r *UnreachableWorkerRetryer) IsRetryable(err error) bool {
+	if ClassifyFailure(err) == FailureCapacity {
+		return false
+	}
+
 	if _, ok := err.(WorkerUnreachableError); ok {
 		return true
 	}
@@ -2,6 +2,8 @@ package transport_test
 
 import (
 	"errors"
+	"net"
+	"syscall"
 
 	"github.com/concourse/atc/worker/transport"
 	"github.com/concourse/retryhttp"
@@ -35,6 +37,26 @@ var _ = Describe("UnreachableWorkerRetryer", func() {
 			Expect(retryer.IsRetryable(err)).To(BeTrue())
 		})
 
+		It("returns false when the worker lacks capacity", func() {
+			delegateRetryer.IsRetryableReturns(true)
+			Expect(retryer.IsRetryable(transport.ErrLoadShed)).To(BeFalse())
+			Expect(retryer.IsRetryable(transport.WorkerDrainingError{WorkerName: "foo"})).To(BeFalse())
+			Expect(delegateRetryer.IsRetryableCallCount()).To(BeZero())
+		})
+
+		It("delegates timed out requests to DelegateRetryer", func() {
+			delegateRetryer.IsRetryableReturns(false)
+			Expect(retryer.IsRetryable(transport.ErrRequestTimedOut)).To(BeFalse())
+			Expect(delegateRetryer.IsRetryableCallCount()).To(Equal(1))
+		})
+
+		It("delegates network errors to DelegateRetryer", func() {
+			err := &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}
+			delegateRetryer.IsRetryableReturns(false)
+			Expect(retryer.IsRetryable(err)).To(BeFalse())
+			Expect(delegateRetryer.IsRetryableCallCount()).To(Equal(1))
+		})
+
 		It("delegates to DelegateRetryer if errors is not WorkerUnreachableError", func() {
 			err := errors.New("some-other-error")
 			delegateRetryer.IsRetryableReturns(true)
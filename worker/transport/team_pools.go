@@ -0,0 +1,69 @@
+package transport
+
+import (
+	"net/http"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// TeamPools gives each team its own connection cap and queue for every
+// worker, so that one team's requests can not exhaust the connections to a
+// worker available to another team. All other worker state, e.g. draining,
+// maintenance windows, outlier ejection, error rates, load shedding and
+// tunnels, is shared by every team.
+type TeamPools struct {
+	pool *Pool
+
+	poolsL sync.Mutex
+	pools  map[string]*TeamPool
+}
+
+func NewTeamPools(logger lager.Logger, clock clock.Clock, config PoolConfig) *TeamPools {
+	return &TeamPools{
+		pool:  NewPool(logger, clock, config),
+		pools: map[string]*TeamPool{},
+	}
+}
+
+// Pool returns the pool shared by every team.
+func (pools *TeamPools) Pool() *Pool {
+	return pools.pool
+}
+
+// ForTeam returns the pool for the named team's requests.
+func (pools *TeamPools) ForTeam(teamName string) *TeamPool {
+	pools.poolsL.Lock()
+	defer pools.poolsL.Unlock()
+
+	pool, found := pools.pools[teamName]
+	if !found {
+		pool = &TeamPool{
+			Pool:     pools.pool,
+			teamName: teamName,
+		}
+
+		pools.pools[teamName] = pool
+	}
+
+	return pool
+}
+
+// TeamPool is a team's view of the shared Pool. Its requests count against the
+// team's own connection cap and wait in the team's own queue.
+type TeamPool struct {
+	*Pool
+
+	teamName string
+}
+
+func (pool *TeamPool) RoundTripper(workerName string, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return pool.teamRoundTripper(pool.teamName, workerName, innerRoundTripper)
+}
+
+// QueueLength returns the number of the team's requests waiting for a
+// connection to the named worker.
+func (pool *TeamPool) QueueLength(workerName string) int {
+	return pool.teamQueueLength(pool.teamName, workerName)
+}
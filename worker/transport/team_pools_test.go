@@ -0,0 +1,86 @@
+package transport_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TeamPools", func() {
+	var (
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		pools            *transport.TeamPools
+	)
+
+	BeforeEach(func() {
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("some-body")),
+			}, nil
+		}
+
+		pools = transport.NewTeamPools(lagertest.NewTestLogger("test"), fakeclock.NewFakeClock(time.Unix(123, 456)), transport.PoolConfig{
+			MaxConnectionsPerWorker: 1,
+		})
+	})
+
+	It("returns the same pool for the same team", func() {
+		Expect(pools.ForTeam("some-team")).To(BeIdenticalTo(pools.ForTeam("some-team")))
+	})
+
+	It("does not let one team's requests exhaust another team's connections to a worker", func() {
+		someTeamRoundTripper := pools.ForTeam("some-team").RoundTripper("some-worker", fakeRoundTripper)
+		otherTeamRoundTripper := pools.ForTeam("other-team").RoundTripper("some-worker", fakeRoundTripper)
+
+		response, err := someTeamRoundTripper.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+
+		go someTeamRoundTripper.RoundTrip(newBodyRequest(""))
+		Eventually(func() int {
+			return pools.ForTeam("some-team").QueueLength("some-worker")
+		}).Should(Equal(1))
+
+		otherResponse, err := otherTeamRoundTripper.RoundTrip(newBodyRequest(""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherResponse.Body.Close()).To(Succeed())
+
+		Expect(pools.ForTeam("other-team").QueueLength("some-worker")).To(BeZero())
+
+		Expect(response.Body.Close()).To(Succeed())
+		Eventually(func() int {
+			return pools.ForTeam("some-team").QueueLength("some-worker")
+		}).Should(BeZero())
+	})
+
+	It("drains a worker for every team", func() {
+		pools.ForTeam("some-team").DrainWorker("some-worker", time.Minute)
+
+		for _, teamName := range []string{"some-team", "other-team"} {
+			_, err := pools.ForTeam(teamName).RoundTripper("some-worker", fakeRoundTripper).RoundTrip(newBodyRequest(""))
+			Expect(err).To(Equal(transport.WorkerDrainingError{WorkerName: "some-worker"}))
+		}
+
+		Expect(pools.Pool().ResolveWorker("some-worker")).To(HaveOccurred())
+		Expect(fakeRoundTripper.RoundTripCallCount()).To(BeZero())
+	})
+
+	It("applies a worker's maintenance window to every team", func() {
+		pools.ForTeam("some-team").SetMaintenanceWindow("some-worker", transport.MaintenanceWindow{
+			Start: time.Unix(100, 0),
+			End:   time.Unix(200, 0),
+		})
+
+		Expect(pools.ForTeam("other-team").ResolveWorker("some-worker")).To(Equal(transport.ErrWorkerInMaintenance))
+	})
+})
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+type throttlingRoundTripper struct {
+	clock             clock.Clock
+	bytesPerSecond    int64
+	innerRoundTripper http.RoundTripper
+}
+
+// NewThrottlingRoundTripper returns a http.RoundTripper which limits the
+// bandwidth of each request's body and response body, e.g. volume streams,
+// to bytesPerSecond. A bytesPerSecond of zero or less means unlimited.
+func NewThrottlingRoundTripper(clock clock.Clock, bytesPerSecond int64, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	if bytesPerSecond <= 0 {
+		return innerRoundTripper
+	}
+
+	return &throttlingRoundTripper{
+		clock:             clock,
+		bytesPerSecond:    bytesPerSecond,
+		innerRoundTripper: innerRoundTripper,
+	}
+}
+
+func (c *throttlingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		updatedRequest := *request
+		updatedRequest.Body = NewThrottledReadCloser(c.clock, c.bytesPerSecond, request.Body)
+		request = &updatedRequest
+	}
+
+	response, err := c.innerRoundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = NewThrottledReadCloser(c.clock, c.bytesPerSecond, response.Body)
+
+	return response, nil
+}
+
+type throttledReadCloser struct {
+	io.ReadCloser
+
+	clock          clock.Clock
+	bytesPerSecond int64
+
+	started   bool
+	startedAt time.Time
+	read      int64
+}
+
+// NewThrottledReadCloser returns an io.ReadCloser reading from readCloser at
+// no more than bytesPerSecond on average. A bytesPerSecond of zero or less
+// means unlimited, returning readCloser as-is.
+func NewThrottledReadCloser(clock clock.Clock, bytesPerSecond int64, readCloser io.ReadCloser) io.ReadCloser {
+	if bytesPerSecond <= 0 {
+		return readCloser
+	}
+
+	return &throttledReadCloser{
+		ReadCloser:     readCloser,
+		clock:          clock,
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+func (r *throttledReadCloser) Read(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		r.startedAt = r.clock.Now()
+	}
+
+	// never read more than a second's worth at once, so that throughput stays
+	// even for large buffers
+	if int64(len(p)) > r.bytesPerSecond {
+		p = p[:r.bytesPerSecond]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+
+	allowedAt := r.startedAt.Add(time.Duration(float64(r.read) / float64(r.bytesPerSecond) * float64(time.Second)))
+
+	wait := allowedAt.Sub(r.clock.Now())
+	if wait > 0 {
+		r.clock.Sleep(wait)
+	}
+
+	return n, err
+}
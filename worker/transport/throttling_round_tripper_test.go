@@ -0,0 +1,103 @@
+package transport_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ThrottlingRoundTripper #RoundTrip", func() {
+	const bytesPerSecond = 20 * 1024
+
+	var (
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		roundTripper     http.RoundTripper
+		content          string
+	)
+
+	BeforeEach(func() {
+		content = strings.Repeat("x", 10*1024)
+
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripStub = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(content)),
+			}, nil
+		}
+
+		roundTripper = transport.NewThrottlingRoundTripper(clock.NewClock(), bytesPerSecond, fakeRoundTripper)
+	})
+
+	It("keeps the response stream's throughput under the limit", func() {
+		request := newBodyRequest("")
+
+		started := time.Now()
+
+		response, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		streamed, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(streamed)).To(Equal(content))
+
+		throughput := float64(len(streamed)) / time.Since(started).Seconds()
+		Expect(throughput).To(BeNumerically("<=", bytesPerSecond))
+	})
+
+	It("keeps the request stream's throughput under the limit", func() {
+		request := newBodyRequest(content)
+
+		var streamed []byte
+		var elapsed time.Duration
+		fakeRoundTripper.RoundTripStub = func(request *http.Request) (*http.Response, error) {
+			started := time.Now()
+
+			var err error
+			streamed, err = ioutil.ReadAll(request.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			elapsed = time.Since(started)
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+
+		_, err := roundTripper.RoundTrip(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(streamed)).To(Equal(content))
+
+		throughput := float64(len(streamed)) / elapsed.Seconds()
+		Expect(throughput).To(BeNumerically("<=", bytesPerSecond))
+	})
+
+	Context("when the limit is zero", func() {
+		BeforeEach(func() {
+			roundTripper = transport.NewThrottlingRoundTripper(clock.NewClock(), 0, fakeRoundTripper)
+		})
+
+		It("streams unthrottled", func() {
+			response, err := roundTripper.RoundTrip(newBodyRequest(content))
+			Expect(err).NotTo(HaveOccurred())
+
+			streamed, err := ioutil.ReadAll(response.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(streamed)).To(Equal(content))
+		})
+
+		It("reads the stream as-is", func() {
+			stream := ioutil.NopCloser(strings.NewReader(content))
+			Expect(transport.NewThrottledReadCloser(clock.NewClock(), 0, stream)).To(BeIdenticalTo(stream))
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader carries the W3C trace context of a sampled request.
+const TraceParentHeader = "traceparent"
+
+type tracingRoundTripper struct {
+	sampleRate        float64
+	innerRoundTripper http.RoundTripper
+
+	randL sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewTracingRoundTripper returns a http.RoundTripper which starts a trace for
+// the given fraction (between 0 and 1) of requests by attaching a trace
+// context to them. Requests which already carry a trace context are passed
+// through unchanged.
+func NewTracingRoundTripper(sampleRate float64, innerRoundTripper http.RoundTripper) http.RoundTripper {
+	return &tracingRoundTripper{
+		sampleRate:        sampleRate,
+		innerRoundTripper: innerRoundTripper,
+		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *tracingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get(TraceParentHeader) != "" {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	traceParent, sampled := c.sample()
+	if !sampled {
+		return c.innerRoundTripper.RoundTrip(request)
+	}
+
+	updatedHeader := http.Header{}
+	for k, v := range request.Header {
+		updatedHeader[k] = v
+	}
+
+	updatedHeader.Set(TraceParentHeader, traceParent)
+
+	updatedRequest := *request
+	updatedRequest.Header = updatedHeader
+
+	return c.innerRoundTripper.RoundTrip(&updatedRequest)
+}
+
+func (c *tracingRoundTripper) sample() (string, bool) {
+	c.randL.Lock()
+	defer c.randL.Unlock()
+
+	if c.rand.Float64() >= c.sampleRate {
+		return "", false
+	}
+
+	return fmt.Sprintf(
+		"00-%016x%016x-%016x-01",
+		c.rand.Uint64(),
+		c.rand.Uint64(),
+		c.rand.Uint64(),
+	), true
+}
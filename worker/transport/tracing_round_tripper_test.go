@@ -0,0 +1,87 @@
+package transport_test
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/concourse/atc/worker/transport"
+	"github.com/concourse/atc/worker/transport/transportfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TracingRoundTripper #RoundTrip", func() {
+	var (
+		fakeRoundTripper *transportfakes.FakeRoundTripper
+		request          *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeRoundTripper = new(transportfakes.FakeRoundTripper)
+		fakeRoundTripper.RoundTripReturns(&http.Response{StatusCode: http.StatusTeapot}, nil)
+
+		requestURL, err := url.Parse("http://1.2.3.4/something")
+		Expect(err).NotTo(HaveOccurred())
+
+		request = &http.Request{
+			URL: requestURL,
+		}
+	})
+
+	tracedRequests := func(roundTripper http.RoundTripper, count int) int {
+		for i := 0; i < count; i++ {
+			response, err := roundTripper.RoundTrip(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response).To(Equal(&http.Response{StatusCode: http.StatusTeapot}))
+		}
+
+		traced := 0
+		for i := 0; i < fakeRoundTripper.RoundTripCallCount(); i++ {
+			traceParent := fakeRoundTripper.RoundTripArgsForCall(i).Header.Get(transport.TraceParentHeader)
+			if traceParent != "" {
+				Expect(traceParent).To(MatchRegexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`))
+				traced++
+			}
+		}
+
+		return traced
+	}
+
+	It("approximately honors the sample rate", func() {
+		roundTripper := transport.NewTracingRoundTripper(0.25, fakeRoundTripper)
+		Expect(tracedRequests(roundTripper, 10000)).To(BeNumerically("~", 2500, 250))
+	})
+
+	It("traces every request with a sample rate of 1", func() {
+		roundTripper := transport.NewTracingRoundTripper(1, fakeRoundTripper)
+		Expect(tracedRequests(roundTripper, 100)).To(Equal(100))
+	})
+
+	It("traces no request with a sample rate of 0", func() {
+		roundTripper := transport.NewTracingRoundTripper(0, fakeRoundTripper)
+		Expect(tracedRequests(roundTripper, 100)).To(BeZero())
+	})
+
+	It("does not modify the original request", func() {
+		roundTripper := transport.NewTracingRoundTripper(1, fakeRoundTripper)
+		tracedRequests(roundTripper, 1)
+
+		Expect(request.Header.Get(transport.TraceParentHeader)).To(BeEmpty())
+	})
+
+	Context("when the request already carries a trace context", func() {
+		BeforeEach(func() {
+			request.Header = http.Header{}
+			request.Header.Set(transport.TraceParentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		})
+
+		It("leaves it alone", func() {
+			roundTripper := transport.NewTracingRoundTripper(1, fakeRoundTripper)
+			tracedRequests(roundTripper, 1)
+
+			actualRequest := fakeRoundTripper.RoundTripArgsForCall(0)
+			Expect(actualRequest.Header.Get(transport.TraceParentHeader)).To(Equal("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package transport_test
 
 import (
+	"github.com/concourse/atc/metric"
+	"github.com/concourse/atc/metric/metricfakes"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +14,16 @@ func TestTransport(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Transport Suite")
 }
+
+var fakeEmitter *metricfakes.FakeEmitter
+
+var _ = BeforeSuite(func() {
+	fakeEmitter = new(metricfakes.FakeEmitter)
+
+	emitterFactory := new(metricfakes.FakeEmitterFactory)
+	emitterFactory.IsConfiguredReturns(true)
+	emitterFactory.NewEmitterReturns(fakeEmitter, nil)
+
+	metric.RegisterEmitter(emitterFactory)
+	Expect(metric.Initialize(nil, "test", map[string]string{})).To(Succeed())
+})
@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// TunnelProber periodically probes the tunnels of a Pool while it runs, so
+// that dead idle connections are evicted before a request reuses them.
+type TunnelProber struct {
+	pool     *Pool
+	clock    clock.Clock
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewTunnelProber(pool *Pool, clock clock.Clock, interval time.Duration, timeout time.Duration) *TunnelProber {
+	return &TunnelProber{
+		pool:     pool,
+		clock:    clock,
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+func (prober *TunnelProber) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := prober.clock.NewTicker(prober.interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			prober.pool.ProbeTunnels(prober.timeout)
+		case <-signals:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package transport_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/concourse/atc/worker/transport"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TunnelProber", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		pool      *transport.Pool
+
+		tunnel  *httptest.Server
+		probesL sync.Mutex
+		probes  int
+
+		signals chan os.Signal
+		exited  chan error
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		pool = transport.NewPool(lagertest.NewTestLogger("test"), fakeClock, transport.PoolConfig{})
+
+		probes = 0
+		tunnel = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			probesL.Lock()
+			probes++
+			probesL.Unlock()
+		}))
+
+		tunnelURL, err := url.Parse(tunnel.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		pool.TunnelRoundTripper(tunnelURL)
+
+		signals = make(chan os.Signal)
+		exited = make(chan error, 1)
+
+		ready := make(chan struct{})
+		go func() {
+			exited <- transport.NewTunnelProber(pool, fakeClock, time.Minute, time.Second).Run(signals, ready)
+		}()
+
+		Eventually(ready).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		tunnel.Close()
+	})
+
+	It("probes the pool's tunnels at every interval until signalled", func() {
+		receivedProbes := func() int {
+			probesL.Lock()
+			defer probesL.Unlock()
+			return probes
+		}
+
+		Consistently(receivedProbes).Should(BeZero())
+
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		Eventually(receivedProbes).Should(Equal(1))
+
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		Eventually(receivedProbes).Should(Equal(2))
+
+		close(signals)
+		Eventually(exited).Should(Receive(BeNil()))
+	})
+})